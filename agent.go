@@ -1,16 +1,19 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"os"
+	"sync"
 	"time"
 )
 
 type Node struct {
-	state       *GameState
+	state       *GameState // The state the action leads to, in open loop only its latest sample
 	action      int
 	visitCount  int
 	totalReward float64
@@ -20,15 +23,160 @@ type Node struct {
 
 type Tree struct {
 	Root        *Node
-	Simulations int
+	Simulations int   // Simulations run over the lifetime of the tree
+	Seed        int64 // Seed of the agent that grew the tree
+
+	nodes int // Nodes currently in the tree
 }
 
 type Agent struct {
-	Tree *Tree
+	Tree       *Tree
+	Config     AgentConfig
+	LastSearch SearchStats // What the last SelectAction call did
+
+	mu         sync.Mutex   // Guards the tree during shared-tree parallel search
+	rng        *rand.Rand   // Draws the search's random choices, seeded from Config.Seed
+	workerRNGs []*rand.Rand // One generator per parallel rollout worker, drawn from rng
+}
+
+// searchIterations is the number of simulations per decision when no other limit is configured.
+const searchIterations = 1000
+
+// AgentConfig tunes how the agent searches.
+// A search stops at whichever of Iterations, TimeBudget and MaxNodes is reached first;
+// a zero value disables that limit. Searches with the same Seed choose the same actions,
+// unless a TimeBudget or tree parallelism lets timing decide how far the search gets.
+type AgentConfig struct {
+	Iterations   int           // Simulations per decision
+	TimeBudget   time.Duration // Wall-clock time per decision
+	MaxNodes     int           // Nodes the tree may grow to, roughly 100 bytes each
+	RolloutDepth int           // Ticks simulated per rollout
+	Reward       Reward        // Scores rollouts, GymReward when nil
+	Rollout      RolloutPolicy // Picks rollout actions, RandomPolicy when nil
+	Parallelism  Parallelism   // How the search is spread over goroutines
+	Workers      int           // Goroutines for parallel search, 0 means one per CPU
+	VirtualLoss  float64       // Reward withheld from nodes being explored by another worker (tree parallelism)
+	OpenLoop     bool          // Plan action sequences, sampling the wind's gusts instead of assuming the wind holds
+	Seed         int64         // Seeds every random choice of the search
+}
+
+// DefaultAgentConfig is a single-threaded search of 1000 simulations.
+func DefaultAgentConfig() AgentConfig {
+	return AgentConfig{
+		Iterations:   searchIterations,
+		RolloutDepth: 100,
+		Reward:       GymReward{},
+		Rollout:      RandomPolicy{},
+		Parallelism:  Sequential,
+		VirtualLoss:  100,
+	}
+}
+
+// autopilotTimeBudget leaves room in a 60 TPS frame, 16.7ms, for physics and drawing.
+const autopilotTimeBudget = 12 * time.Millisecond
+
+// AutopilotAgentConfig is the default search with a time budget that fits in one game frame,
+// so the autopilot does not hold up the game. The iteration limit still ends easy searches early.
+func AutopilotAgentConfig() AgentConfig {
+	config := DefaultAgentConfig()
+	config.TimeBudget = autopilotTimeBudget
+	return config
+}
+
+// SearchStats reports what a single SelectAction call did.
+type SearchStats struct {
+	Simulations   int // Simulations over all workers
+	Elapsed       time.Duration
+	Nodes         int // Size of the agent's tree after the search, the one kept for the next decision
+	SearchedNodes int // Nodes over all the trees searched, with root parallelism the workers' own trees too
+}
+
+// searchBudget holds the limits of a single SelectAction call.
+type searchBudget struct {
+	iterations int
+	deadline   time.Time // Zero for no time limit
+	maxNodes   int       // Zero for no node limit
+}
+
+// budget turns the configured limits into a budget for a search starting now.
+func (a *Agent) budget() searchBudget {
+	b := searchBudget{iterations: a.Config.Iterations, maxNodes: a.Config.MaxNodes}
+	if a.Config.TimeBudget > 0 {
+		b.deadline = time.Now().Add(a.Config.TimeBudget)
+	}
+	if b.iterations <= 0 {
+		b.iterations = math.MaxInt
+		if b.deadline.IsZero() && b.maxNodes <= 0 {
+			b.iterations = searchIterations
+		}
+	}
+	return b
+}
+
+// allows checks if another simulation fits in the budget.
+// The first simulation is always allowed so there is an action to pick.
+func (b searchBudget) allows(done, nodes int) bool {
+	if done >= b.iterations {
+		return false
+	}
+	if done == 0 {
+		return true
+	}
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return false
+	}
+	return b.maxNodes <= 0 || nodes < b.maxNodes
+}
+
+// agentFlags registers the search settings on a flag set, defaulting to defaults.
+// The returned function builds the configuration once the flags are parsed.
+func agentFlags(flags *flag.FlagSet, defaults AgentConfig) func() (AgentConfig, error) {
+	iterations := flags.Int("iterations", defaults.Iterations, "MCTS simulations per decision, 0 for no limit")
+	timeBudget := flags.Duration("time-budget", defaults.TimeBudget, "MCTS wall-clock time per decision, e.g. 15ms, 0 for no limit")
+	maxNodes := flags.Int("max-nodes", defaults.MaxNodes, "MCTS tree size limit, 0 for no limit")
+	rolloutDepth := flags.Int("rollout-depth", defaults.RolloutDepth, "ticks simulated per MCTS rollout")
+	reward := flags.String("reward", "gym", "MCTS rollout reward: gym, sparse or potential")
+	rollout := flags.String("rollout", "random", "MCTS rollout policy: random, heuristic or mixed")
+	epsilon := flags.Float64("epsilon", 0.2, "share of random actions in the mixed rollout policy")
+	parallel := flags.String("parallel", "none", "MCTS parallelism: none, root, leaf or tree")
+	workers := flags.Int("workers", defaults.Workers, "goroutines for parallel MCTS, 0 for one per CPU")
+	openLoop := flags.Bool("open-loop", defaults.OpenLoop, "MCTS plans action sequences and samples wind gusts")
+
+	return func() (AgentConfig, error) {
+		config := defaults
+		config.Iterations = *iterations
+		config.TimeBudget = *timeBudget
+		config.MaxNodes = *maxNodes
+		config.RolloutDepth = *rolloutDepth
+		config.Workers = *workers
+		config.OpenLoop = *openLoop
+		var err error
+		if config.Reward, err = ParseReward(*reward); err != nil {
+			return config, err
+		}
+		if config.Rollout, err = ParseRolloutPolicy(*rollout, *epsilon); err != nil {
+			return config, err
+		}
+		config.Parallelism, err = ParseParallelism(*parallel)
+		return config, err
+	}
 }
 
 func NewAgent(initialState *GameState) *Agent {
+	return NewAgentWithConfig(initialState, DefaultAgentConfig())
+}
+
+// NewAgentWithConfig creates an agent searching from initialState with the given configuration.
+func NewAgentWithConfig(initialState *GameState, config AgentConfig) *Agent {
+	if config.Reward == nil {
+		config.Reward = GymReward{}
+	}
+	if config.Rollout == nil {
+		config.Rollout = RandomPolicy{}
+	}
 	return &Agent{
+		Config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
 		Tree: &Tree{
 			Root: &Node{
 				state:       initialState,
@@ -38,36 +186,160 @@ func NewAgent(initialState *GameState) *Agent {
 				parent:      nil,
 			},
 			Simulations: 0,
+			Seed:        config.Seed,
+			nodes:       1,
 		},
 	}
 }
 
 func (a *Agent) SelectAction() int {
 	// Perform MCTS to select the best action
-	for i := 0; i < 1000; i++ { // Number of simulations
-		node := a.treePolicy(a.Tree.Root)
-		reward := a.simulate(node.state)
-		a.backpropagate(node, reward)
+	start := time.Now()
+	budget := a.budget()
+	var simulations int
+	var votes []int  // Root-parallel visits per action over all the workers' trees
+	workerNodes := 0 // Nodes of the root-parallel workers' own trees
+	switch a.Config.Parallelism {
+	case RootParallel:
+		simulations, votes, workerNodes = a.searchRootParallel(budget)
+	case LeafParallel:
+		simulations = a.searchLeafParallel(budget)
+	case TreeParallel:
+		simulations = a.searchTreeParallel(budget)
+	default:
+		simulations = a.search(budget)
+	}
+	a.Tree.Simulations += simulations
+	a.LastSearch = SearchStats{
+		Simulations:   simulations,
+		Elapsed:       time.Since(start),
+		Nodes:         a.Tree.nodes,
+		SearchedNodes: a.Tree.nodes + workerNodes,
 	}
 
 	// Choose the best action based on visit count
 	bestAction := -1
 	maxVisits := -1
 	for _, child := range a.Tree.Root.children {
-		if child.visitCount > maxVisits {
-			maxVisits = child.visitCount
+		visits := child.visitCount
+		if votes != nil {
+			visits = votes[child.action]
+		}
+		if visits > maxVisits {
+			maxVisits = visits
 			bestAction = child.action
 		}
 	}
 	return bestAction
 }
 
-func (a *Agent) treePolicy(node *Node) *Node {
-	// Expand the tree or select the best child
-	if len(node.children) == 0 {
-		return a.expand(node)
+// search runs single-threaded simulations on the agent's tree and returns how many ran.
+func (a *Agent) search(budget searchBudget) int {
+	done := 0
+	for ; budget.allows(done, a.Tree.nodes); done++ {
+		leaf := a.treePolicy(a.Tree.Root)
+		reward := a.simulate(leaf, a.rng)
+		a.backpropagate(leaf.node, reward)
+	}
+	return done
+}
+
+// leaf is where a simulation leaves the tree: the node reached, the state it was reached in,
+// the gust blowing there and the reward collected on the way down from the root.
+type leaf struct {
+	node   *Node
+	state  *GameState
+	gust   float64
+	reward float64
+}
+
+// treePolicy descends through expanded nodes and expands the first leaf reached.
+// Every level picks its child by UCB1, so the tree deepens along promising action sequences
+// and a subtree kept by Advance holds a plan several ticks long rather than a single step.
+// Closed-loop nodes hold the state their action leads to. Open-loop nodes stand for the
+// action sequence from the root, every descent simulates it afresh with newly sampled gusts.
+func (a *Agent) treePolicy(root *Node) leaf {
+	l := leaf{node: root, state: root.state, gust: root.state.ground().Wind.gust}
+	for !l.state.IsDone() {
+		expanded := len(l.node.children) == 0
+		if expanded {
+			l.node = a.expand(l.node)
+		} else {
+			l.node = a.bestChild(l.node, true)
+		}
+
+		next := l.node.state
+		if a.Config.OpenLoop {
+			next, l.gust = a.transition(l.state, l.node.action, l.gust, a.rng)
+			l.node.state = next
+		}
+		l.reward += a.Config.Reward.Reward(l.state, next, ActionControls(l.node.action))
+		l.state = next
+		if expanded {
+			break
+		}
+	}
+	return l
+}
+
+// transition is the planner's model of a tick: the state after taking action from state with
+// gust blowing, and the gust blowing on the tick after. Closed loop it is the deterministic Step,
+// with the wind blowing on as it does now. Open loop the next gust is sampled from rng.
+func (a *Agent) transition(state *GameState, action int, gust float64, rng *rand.Rand) (*GameState, float64) {
+	if !a.Config.OpenLoop {
+		return state.Step(action), gust
+	}
+	wind := state.ground().Wind
+	next, _ := stepPhysics(state, ActionControls(action), wind.Bias+gust)
+	return next, wind.nextGust(gust, rng.NormFloat64())
+}
+
+// AdvanceStats reports how much search was carried over by Advance.
+type AdvanceStats struct {
+	Reused         bool // The executed action's subtree became the new root
+	RetainedVisits int  // Visits of the new root kept from the previous search
+	RetainedNodes  int  // Nodes in the new tree
+	DiscardedNodes int  // Nodes dropped with the old root and its other children
+}
+
+// Advance moves the agent past an executed action.
+// The matching child becomes the new root and its siblings are discarded, so the next
+// SelectAction continues the earlier search. If the observed state differs from the
+// one the tree predicted, the search starts over from the observed state. Open-loop
+// statistics already average over the outcomes of the action, so the child is kept anyway.
+func (a *Agent) Advance(action int, observed *GameState) AdvanceStats {
+	stats := AdvanceStats{DiscardedNodes: a.Tree.Root.size()}
+
+	var next *Node
+	for _, child := range a.Tree.Root.children {
+		if child.action == action && (a.Config.OpenLoop || child.state.Matches(observed)) {
+			next = child
+			break
+		}
+	}
+
+	if next == nil {
+		next = &Node{}
+	} else {
+		stats.Reused = true
+		stats.RetainedVisits = next.visitCount
+		stats.RetainedNodes = next.size()
+		stats.DiscardedNodes -= stats.RetainedNodes
+	}
+	next.state = observed
+	next.parent = nil
+	a.Tree.Root = next
+	a.Tree.nodes = max(stats.RetainedNodes, 1)
+	return stats
+}
+
+// size counts the nodes in the subtree below and including n.
+func (n *Node) size() int {
+	count := 1
+	for _, child := range n.children {
+		count += child.size()
 	}
-	return a.bestChild(node, true)
+	return count
 }
 
 func (a *Agent) expand(node *Node) *Node {
@@ -84,8 +356,9 @@ func (a *Agent) expand(node *Node) *Node {
 		}
 		node.children = append(node.children, child)
 	}
+	a.Tree.nodes += len(node.children)
 	// Return a random child for now
-	return node.children[rand.Intn(len(node.children))]
+	return node.children[a.rng.Intn(len(node.children))]
 }
 
 func (a *Agent) bestChild(node *Node, useExploration bool) *Node {
@@ -106,48 +379,22 @@ func (a *Agent) bestChild(node *Node, useExploration bool) *Node {
 	return bestChild
 }
 
-func (a *Agent) simulate(state *GameState) float64 {
-	// Simulate a random rollout
-	simulatedState := state.Copy()
-	totalReward := 0.0
-	for i := 0; i < 100; i++ { // Limit the simulation depth
-		if simulatedState.IsDone() {
-			break
-		}
-		action := rand.Intn(4)
-		simulatedState = simulatedState.Step(action)
-
-		// Proximity to the landing pad
-		distance := math.Sqrt(math.Pow(simulatedState.LanderX-0, 2) + math.Pow(simulatedState.LanderY-400, 2))
-		totalReward -= distance * 0.1
+// simulate scores a simulation: the reward collected on the way down the tree,
+// plus a rollout from the state it left the tree in, drawing its random choices from rng.
+func (a *Agent) simulate(l leaf, rng *rand.Rand) float64 {
+	totalReward := l.reward
 
-		// Speed
-		speed := math.Sqrt(math.Pow(simulatedState.VelocityX, 2) + math.Pow(simulatedState.VelocityY, 2))
-		totalReward -= speed * 0.1
-
-		// Angle
-		totalReward -= math.Abs(simulatedState.Angle) * 0.1
-
-		// Leg Contact
-		if simulatedState.LanderY >= 400 {
-			totalReward += 10
-		}
-
-		// Engine Usage
-		if action == 1 || action == 3 { // Side engine
-			totalReward -= 0.03
-		} else if action == 2 { // Main engine
-			totalReward -= 0.3
-		}
-
-		// Episode Outcome
+	// Simulate a rollout with the configured policy
+	simulatedState, gust := l.state, l.gust
+	for i := 0; i < a.Config.RolloutDepth; i++ { // Limit the simulation depth
 		if simulatedState.IsDone() {
-			if simulatedState.IsSafeLanding() {
-				totalReward += 100
-			} else {
-				totalReward -= 100
-			}
+			break
 		}
+		action := a.Config.Rollout.Action(simulatedState, rng)
+		var nextState *GameState
+		nextState, gust = a.transition(simulatedState, action, gust, rng)
+		totalReward += a.Config.Reward.Reward(simulatedState, nextState, ActionControls(action))
+		simulatedState = nextState
 	}
 	return totalReward
 }
@@ -161,44 +408,77 @@ func (a *Agent) backpropagate(node *Node, reward float64) {
 	}
 }
 
+// SaveTreeToFile writes the search tree as versioned JSON to filename + ".json".
 func (a *Agent) SaveTreeToFile(filename string) error {
-	// Serialize the tree to a file (e.g., JSON format)
+	return a.saveTree(a.treeFileName(filename)+".json", writeTreeJSON)
+}
+
+// SaveTreeToBinaryFile writes the search tree in the compact binary format to filename + ".bin".
+func (a *Agent) SaveTreeToBinaryFile(filename string) error {
+	return a.saveTree(a.treeFileName(filename)+".bin", writeTreeBinary)
+}
+
+// LoadTreeFromFile replaces the search tree with one saved by SaveTreeToFile.
+func (a *Agent) LoadTreeFromFile(filename string) error {
+	return a.loadTree(filename+".json", readTreeJSON)
+}
+
+// LoadTreeFromBinaryFile replaces the search tree with one saved by SaveTreeToBinaryFile.
+func (a *Agent) LoadTreeFromBinaryFile(filename string) error {
+	return a.loadTree(filename+".bin", readTreeBinary)
+}
 
-	// if filename == "" then filename = date_time_rand4
+// treeFileName picks a date_time_seed name when none is given.
+func (a *Agent) treeFileName(filename string) string {
 	if filename == "" {
 		date_str := time.Now().Format("20060102_150405")
-		rand_suffix := rand.Intn(1000)
-		filename = date_str + "_" + fmt.Sprintf("%03d", rand_suffix)
+		filename = date_str + "_" + fmt.Sprintf("%d", a.Tree.Seed)
 	}
+	return filename
+}
 
-	fileName := filename + ".json"
+func (a *Agent) saveTree(fileName string, write func(io.Writer, *Tree) error) error {
 	file, err := os.Create(fileName)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(a.Tree)
-	if err != nil {
+	buffered := bufio.NewWriter(file)
+	if err := write(buffered, a.Tree); err != nil {
 		return err
 	}
-	return nil
+	return buffered.Flush()
 }
 
-func (a *Agent) LoadTreeFromFile(filename string) error {
-	// Deserialize the tree from a file
-	fileName := filename + ".json"
+func (a *Agent) loadTree(fileName string, read func(io.Reader) (*Tree, error)) error {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(a.Tree)
+	tree, err := read(bufio.NewReader(file))
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", fileName, err)
 	}
+
+	// Terrain is not saved, loaded states collide with the agent's current terrain
+	if a.Tree.Root != nil && a.Tree.Root.state != nil && a.Tree.Root.state.Terrain != nil {
+		tree.setTerrain(a.Tree.Root.state.Terrain)
+	}
+	a.Tree = tree
 	return nil
 }
+
+// setTerrain points every state in the tree at env.
+func (t *Tree) setTerrain(env *Environment) {
+	var visit func(node *Node)
+	visit = func(node *Node) {
+		node.state.Terrain = env
+		for _, child := range node.children {
+			visit(child)
+		}
+	}
+	visit(t.Root)
+}
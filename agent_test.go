@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"math"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAgentSelectAction(t *testing.T) {
@@ -20,3 +27,253 @@ func TestAgentSelectAction(t *testing.T) {
 		t.Errorf("Invalid action selected: %d", action)
 	}
 }
+
+func TestAgentFlagDefaults(t *testing.T) {
+	parse := func(defaults AgentConfig) AgentConfig {
+		config, err := agentFlags(flag.NewFlagSet("test", flag.ContinueOnError), defaults)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return config
+	}
+
+	// Test case 1: The game's autopilot plans within a frame at 60 TPS
+	autopilot := parse(AutopilotAgentConfig())
+	if autopilot.TimeBudget <= 0 || autopilot.TimeBudget >= time.Second/60 {
+		t.Errorf("Expected the autopilot to plan within a frame, but got a budget of %v", autopilot.TimeBudget)
+	}
+	if NewAgentWithConfig(&GameState{}, autopilot).budget().deadline.IsZero() {
+		t.Errorf("Expected the autopilot's searches to have a deadline")
+	}
+
+	// Test case 2: The headless runner only counts iterations, so its runs repeat
+	runner := parse(DefaultAgentConfig())
+	if runner.TimeBudget != 0 || runner.Iterations != searchIterations {
+		t.Errorf("Expected %d iterations without a time budget, but got %+v", searchIterations, runner)
+	}
+}
+
+func TestSaveAndLoadTree(t *testing.T) {
+	env := NewEnvironment()
+	config := DefaultAgentConfig()
+	config.Seed = 42
+	agent := NewAgentWithConfig(&GameState{LanderX: StartX, LanderY: 100, Terrain: env}, config)
+	agent.SelectAction()
+	want := agent.Tree.records()
+
+	for _, format := range []string{"json", "binary"} {
+		filename := filepath.Join(t.TempDir(), "tree")
+		var err error
+		if format == "json" {
+			err = agent.SaveTreeToFile(filename)
+		} else {
+			err = agent.SaveTreeToBinaryFile(filename)
+		}
+		if err != nil {
+			t.Fatalf("%s: save failed: %v", format, err)
+		}
+
+		loaded := NewAgent(&GameState{Terrain: env})
+		if format == "json" {
+			err = loaded.LoadTreeFromFile(filename)
+		} else {
+			err = loaded.LoadTreeFromBinaryFile(filename)
+		}
+		if err != nil {
+			t.Fatalf("%s: load failed: %v", format, err)
+		}
+
+		if loaded.Tree.Seed != 42 || loaded.Tree.Simulations != agent.Tree.Simulations {
+			t.Errorf("%s: expected seed 42 and %d simulations, but got %d and %d",
+				format, agent.Tree.Simulations, loaded.Tree.Seed, loaded.Tree.Simulations)
+		}
+		got := loaded.Tree.records()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %d nodes, but got %d", format, len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: node %d differs: %+v vs %+v", format, i, got[i], want[i])
+			}
+		}
+		for _, child := range loaded.Tree.Root.children {
+			if child.parent != loaded.Tree.Root {
+				t.Errorf("%s: child of the root lost its parent link", format)
+			}
+		}
+
+		// The loaded tree keeps searching where the saved one stopped
+		if action := loaded.SelectAction(); action < 0 || action > 3 {
+			t.Errorf("%s: invalid action after loading: %d", format, action)
+		}
+	}
+}
+
+func TestLoadTreeRejectsOtherVersions(t *testing.T) {
+	_, err := readTreeJSON(strings.NewReader(`{"version": 99, "nodes": [{"parent": -1}]}`))
+	if err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+
+	_, err = readTreeJSON(strings.NewReader(`{"version": 5, "nodes": [{"parent": -1}, {"parent": 5}]}`))
+	if err == nil {
+		t.Errorf("Expected an error for a dangling parent index")
+	}
+
+	// A header claiming billions of nodes fails on the missing records without allocating them
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, binaryHeader{Magic: treeMagic, Version: treeFormatVersion, NodeCount: math.MaxUint32})
+	if _, err := readTreeBinary(&buf); err == nil {
+		t.Errorf("Expected an error for a node count beyond the file")
+	}
+}
+
+func TestTreePolicyDescends(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.Iterations = 200
+	agent := NewAgentWithConfig(&GameState{LanderX: StartX, LanderY: 100}, config)
+	agent.SelectAction()
+	root := agent.Tree.Root
+
+	// Test case 1: The tree grows below the root's children.
+	// Selecting at the root only would stop at the root and its four children.
+	var depth func(n *Node) int
+	depth = func(n *Node) int {
+		deepest := 0
+		for _, child := range n.children {
+			deepest = max(deepest, depth(child)+1)
+		}
+		return deepest
+	}
+	if d := depth(root); d < 3 {
+		t.Errorf("Expected the search to reach at least 3 levels deep, but it stopped at %d", d)
+	}
+
+	// Test case 2: A descent follows UCB1 at every level down to the first unexpanded node,
+	// then expands it
+	want := root
+	for !want.state.IsDone() && len(want.children) > 0 {
+		want = agent.bestChild(want, true)
+	}
+	if got := agent.treePolicy(root).node; got.parent != want {
+		t.Errorf("Expected the descent to expand the node UCB1 leads to")
+	}
+}
+
+func TestAgentAdvance(t *testing.T) {
+	start := &GameState{LanderX: StartX, LanderY: 100}
+	agent := NewAgent(start)
+	action := agent.SelectAction()
+	totalNodes := agent.Tree.Root.size()
+
+	// Test case 1: The observed state matches the prediction
+	stats := agent.Advance(action, start.Step(action))
+	if !stats.Reused || stats.RetainedVisits == 0 {
+		t.Fatalf("Expected the chosen subtree to be reused, but got %+v", stats)
+	}
+	if stats.RetainedNodes+stats.DiscardedNodes != totalNodes {
+		t.Errorf("Expected %d nodes in total, but got %+v", totalNodes, stats)
+	}
+	if agent.Tree.Root.parent != nil || agent.Tree.Root.visitCount != stats.RetainedVisits {
+		t.Errorf("Expected the new root to be detached with its visits kept")
+	}
+
+	// Searching again adds to the retained visits
+	agent.SelectAction()
+	if agent.Tree.Root.visitCount <= stats.RetainedVisits {
+		t.Errorf("Expected the search to continue from %d visits, but got %d", stats.RetainedVisits, agent.Tree.Root.visitCount)
+	}
+
+	// Test case 2: Something unexpected happened, the search starts over
+	surprise := &GameState{LanderX: 100, LanderY: 100}
+	stats = agent.Advance(0, surprise)
+	if stats.Reused || agent.Tree.Root.visitCount != 0 || agent.Tree.Root.state != surprise {
+		t.Errorf("Expected a fresh root for an unexpected state, but got %+v", stats)
+	}
+}
+
+func TestOpenLoopSearch(t *testing.T) {
+	env := NewEnvironment()
+	env.Wind = Wind{Turbulence: 0.05, Seed: 1}
+	env.Update()
+	start := &GameState{LanderX: StartX, LanderY: 100, Terrain: env}
+	config := DefaultAgentConfig()
+	config.Iterations = 200
+	config.OpenLoop = true
+	agent := NewAgentWithConfig(start, config)
+
+	// Test case 1: Descents through the same actions sample different gusts.
+	// The gust blowing now is known, so the samples differ from the second tick on.
+	agent.SelectAction()
+	mostVisited := func(n *Node) *Node {
+		best := n.children[0]
+		for _, child := range n.children {
+			if child.visitCount > best.visitCount {
+				best = child
+			}
+		}
+		return best
+	}
+	grandchild := mostVisited(mostVisited(agent.Tree.Root))
+	sample, visits := grandchild.state, grandchild.visitCount
+	action := agent.SelectAction()
+	if grandchild.visitCount == visits || grandchild.state.Matches(sample) {
+		t.Errorf("Expected a new sample of the state on every descent, but got %+v again", sample)
+	}
+
+	// Test case 2: The root's children share its visits
+	childVisits := 0
+	for _, child := range agent.Tree.Root.children {
+		childVisits += child.visitCount
+	}
+	if action < 0 || action > 3 || childVisits != agent.Tree.Root.visitCount {
+		t.Errorf("Expected an action backed by the children's %d visits, but got %d with %d root visits",
+			childVisits, action, agent.Tree.Root.visitCount)
+	}
+
+	// Test case 3: The subtree is kept whatever the wind did
+	env.Update()
+	observed := start.Step(action)
+	if stats := agent.Advance(action, observed); !stats.Reused || agent.Tree.Root.state != observed {
+		t.Errorf("Expected the open-loop subtree to be reused from the observed state, but got %+v", stats)
+	}
+}
+
+func TestSearchBudget(t *testing.T) {
+	start := &GameState{LanderX: StartX, LanderY: 100}
+
+	// Test case 1: Iteration limit
+	config := DefaultAgentConfig()
+	config.Iterations = 50
+	agent := NewAgentWithConfig(start, config)
+	agent.SelectAction()
+	agent.SelectAction()
+	if agent.LastSearch.Simulations != 50 || agent.Tree.Simulations != 100 {
+		t.Errorf("Expected 50 simulations per call and 100 in total, but got %d and %d",
+			agent.LastSearch.Simulations, agent.Tree.Simulations)
+	}
+
+	// Test case 2: Time limit only
+	config = DefaultAgentConfig()
+	config.Iterations = 0
+	config.TimeBudget = 20 * time.Millisecond
+	agent = NewAgentWithConfig(start, config)
+	agent.SelectAction()
+	if agent.LastSearch.Simulations == 0 || agent.LastSearch.Elapsed > time.Second {
+		t.Errorf("Expected the search to stop near its deadline, but got %+v", agent.LastSearch)
+	}
+
+	// Test case 3: Node limit hits before the iteration limit
+	config = DefaultAgentConfig()
+	config.MaxNodes = 41
+	agent = NewAgentWithConfig(start, config)
+	if action := agent.SelectAction(); action < 0 || action > 3 {
+		t.Errorf("Invalid action selected: %d", action)
+	}
+	if agent.LastSearch.Nodes != 41 || agent.LastSearch.Simulations != 10 {
+		t.Errorf("Expected 10 simulations to grow the tree to 41 nodes, but got %+v", agent.LastSearch)
+	}
+	if agent.Tree.Root.size() != agent.LastSearch.Nodes {
+		t.Errorf("Expected a node count of %d, but the tree has %d", agent.LastSearch.Nodes, agent.Tree.Root.size())
+	}
+}
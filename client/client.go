@@ -0,0 +1,186 @@
+// Package client drives a lander server, started with `lander serve`, over its JSON-lines protocol.
+//
+// Each request is a JSON object on its own line with a "cmd" of reset, step, observe, render,
+// spaces or close, and is answered by one JSON line. A connection is a session with its own
+// environment; open one client per concurrent episode.
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Observation is the lander's 8-dimensional state vector: x, y, x and y velocity, angle,
+// angular velocity, and 1 or 0 for each leg on the ground.
+type Observation [8]float64
+
+// Info carries what an observation leaves out.
+type Info struct {
+	Seed   int64   `json:"seed"`   // Seed the episode was reset with
+	Tick   int     `json:"tick"`   // Ticks since the reset
+	Reason string  `json:"reason"` // Why the episode ended, "In Air" while flying
+	Fuel   float64 `json:"fuel"`   // Fuel left in the tank
+}
+
+// Step is the outcome of a step.
+type Step struct {
+	Observation Observation
+	Reward      float64
+	Terminated  bool // Landed, crashed or left the screen
+	Truncated   bool // Hit the server's tick limit
+	Info        Info
+}
+
+// Spaces describes the valid actions and the bounds of the observations.
+type Spaces struct {
+	Actions int       // Actions are numbered 0 to Actions-1
+	Low     []float64 // Lowest value of each observation dimension
+	High    []float64 // Highest value of each observation dimension
+}
+
+type request struct {
+	Cmd    string `json:"cmd"`
+	Seed   int64  `json:"seed,omitempty"`
+	Action int    `json:"action,omitempty"`
+}
+
+type response struct {
+	Error       string       `json:"error"`
+	Observation *Observation `json:"observation"`
+	Reward      float64      `json:"reward"`
+	Terminated  bool         `json:"terminated"`
+	Truncated   bool         `json:"truncated"`
+	Info        Info         `json:"info"`
+	PNG         []byte       `json:"png"`
+	ActionSpace struct {
+		N int `json:"n"`
+	} `json:"action_space"`
+	ObservationSpace struct {
+		Low  []float64 `json:"low"`
+		High []float64 `json:"high"`
+	} `json:"observation_space"`
+}
+
+// Client is a session with a lander server. It is safe for concurrent use,
+// requests are sent one at a time.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+// Dial opens a session with the server at address on network, "tcp" or "unix".
+func Dial(network, address string) (*Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn), nil
+}
+
+// New starts a session over an open connection to a server.
+func New(conn net.Conn) *Client {
+	writer := bufio.NewWriter(conn)
+	return &Client{
+		conn:    conn,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+		decoder: json.NewDecoder(bufio.NewReader(conn)),
+	}
+}
+
+// call sends a request and waits for its response.
+func (c *Client) call(req request) (*response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.encoder.Encode(req); err != nil {
+		return nil, err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := c.decoder.Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Reset starts a new episode drawn from seed.
+func (c *Client) Reset(seed int64) (Observation, Info, error) {
+	resp, err := c.call(request{Cmd: "reset", Seed: seed})
+	if err != nil {
+		return Observation{}, Info{}, err
+	}
+	return observation(resp), resp.Info, nil
+}
+
+// Step takes an action and returns what followed.
+func (c *Client) Step(action int) (Step, error) {
+	resp, err := c.call(request{Cmd: "step", Action: action})
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{
+		Observation: observation(resp),
+		Reward:      resp.Reward,
+		Terminated:  resp.Terminated,
+		Truncated:   resp.Truncated,
+		Info:        resp.Info,
+	}, nil
+}
+
+// Observe returns the current observation without advancing the episode.
+func (c *Client) Observe() (Observation, Info, error) {
+	resp, err := c.call(request{Cmd: "observe"})
+	if err != nil {
+		return Observation{}, Info{}, err
+	}
+	return observation(resp), resp.Info, nil
+}
+
+// Render returns the current frame as a PNG image.
+func (c *Client) Render() ([]byte, error) {
+	resp, err := c.call(request{Cmd: "render"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.PNG, nil
+}
+
+// Spaces returns the action and observation spaces.
+func (c *Client) Spaces() (Spaces, error) {
+	resp, err := c.call(request{Cmd: "spaces"})
+	if err != nil {
+		return Spaces{}, err
+	}
+	return Spaces{
+		Actions: resp.ActionSpace.N,
+		Low:     resp.ObservationSpace.Low,
+		High:    resp.ObservationSpace.High,
+	}, nil
+}
+
+// Close ends the session and closes the connection.
+func (c *Client) Close() error {
+	_, err := c.call(request{Cmd: "close"})
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func observation(resp *response) Observation {
+	if resp.Observation == nil {
+		return Observation{}
+	}
+	return *resp.Observation
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"bufio"
+	"net"
+	"testing"
+)
+
+// fakeServer answers every request on a loopback listener with the next canned response,
+// and records the requests.
+func fakeServer(t *testing.T, responses ...string) (*Client, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	requests := make(chan string, len(responses))
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		lines := bufio.NewScanner(conn)
+		for _, resp := range responses {
+			if !lines.Scan() {
+				return
+			}
+			requests <- lines.Text()
+			conn.Write([]byte(resp + "\n"))
+		}
+	}()
+
+	c, err := Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, requests
+}
+
+func TestClient(t *testing.T) {
+	c, requests := fakeServer(t,
+		`{"observation":[0.1,1,0,0,0,0,0,0],"info":{"seed":9,"tick":0,"reason":"In Air","fuel":100}}`,
+		`{"observation":[0.1,0.9,0,-0.1,0,0,0,0],"reward":-0.3,"terminated":false,"truncated":true,"info":{"seed":9,"tick":1,"reason":"In Air","fuel":99.7}}`,
+		`{"error":"action 5 outside the action space"}`,
+		`{"action_space":{"n":4},"observation_space":{"low":[-1],"high":[1]}}`,
+	)
+
+	// Test case 1: Reset sends the seed
+	obs, info, err := c.Reset(9)
+	if err != nil || obs[1] != 1 || info.Seed != 9 || info.Fuel != 100 {
+		t.Errorf("Unexpected reset: %v, %+v (%v)", obs, info, err)
+	}
+	if req := <-requests; req != `{"cmd":"reset","seed":9}` {
+		t.Errorf("Unexpected request %s", req)
+	}
+
+	// Test case 2: Step sends the action and decodes the outcome
+	step, err := c.Step(2)
+	if err != nil || step.Reward != -0.3 || !step.Truncated || step.Info.Tick != 1 || step.Observation[3] != -0.1 {
+		t.Errorf("Unexpected step: %+v (%v)", step, err)
+	}
+	if req := <-requests; req != `{"cmd":"step","action":2}` {
+		t.Errorf("Unexpected request %s", req)
+	}
+
+	// Test case 3: Errors reported by the server
+	if _, err := c.Step(5); err == nil || err.Error() != "action 5 outside the action space" {
+		t.Errorf("Expected the server's error, but got %v", err)
+	}
+	<-requests
+
+	// Test case 4: Spaces
+	if spaces, err := c.Spaces(); err != nil || spaces.Actions != 4 || spaces.Low[0] != -1 || spaces.High[0] != 1 {
+		t.Errorf("Unexpected spaces: %+v (%v)", spaces, err)
+	}
+}
@@ -17,29 +17,54 @@ const (
 	LanderCenterOffsetX = 15.0 // Distance from left edge to center
 	LanderCenterOffsetY = 15.0 // Distance from top edge to center
 	LanderBottomOffset  = 15.0 // Distance from center to bottom (legs)
+	LegWidth            = 5.0  // Legs stand at the lander's left and right edges
+	LegLength           = 10.0 // Legs reach this far below the body
 
 	// Physics constants
-	Gravity    = 0.05
-	MainThrust = 0.1
-	SideThrust = 0.05 // Rotational thrust
+	Gravity        = 0.05
+	MainThrust     = 0.1
+	SideThrust     = 0.004 // Angular acceleration from an orientation engine (radians/tick²)
+	AngularDamping = 0.02  // Share of angular velocity lost every tick, 0 for none
+
+	// Share of a wind gust that carries over to the next tick, gusts build up and die down over dozens of ticks
+	GustCorrelation = 0.95
+
+	// Fuel tank and burn per tick of firing
+	FuelCapacity = 100.0
+	MainFuelBurn = 0.3
+	SideFuelBurn = 0.03
 
 	// Safe landing thresholds
 	SafeVerticalSpeed   = 2.0  // Maximum safe vertical speed
 	SafeHorizontalSpeed = 1.0  // Maximum safe horizontal speed
 	SafeLandingAngle    = 0.26 // Maximum safe angle in radians (~15 degrees)
-)
+	SafeAngularSpeed    = 0.05 // Maximum safe angular velocity in radians/tick
 
-// GetLanderBottomY returns the Y coordinate of the lander's bottom (legs)
-func GetLanderBottomY(landerCenterY float64) float64 {
-	return landerCenterY + LanderBottomOffset
-}
+	// How close a leg tip must be to the ground to count as touching it
+	LegContactTolerance = 2.0
+	// Steepest ground a leg can stand on, as the change in Y per unit of X (~27 degrees)
+	MaxLandingSlope = 0.5
 
-// IsLanderOnGround checks if the lander has touched the ground
-func IsLanderOnGround(landerCenterY float64) bool {
-	return GetLanderBottomY(landerCenterY) >= GroundLevel
-}
+	// Landing legs are spring-dampers along the vertical, the lander has unit mass
+	LegStiffness  = 0.08  // Push per pixel a leg is pressed into the ground
+	LegDamping    = 0.3   // Push per pixel/tick a leg moves into the ground
+	LegFriction   = 0.2   // Share of horizontal velocity lost every tick by each leg on the ground
+	LanderInertia = 150.0 // Moment of inertia turning leg pushes into spin, a 30x30 box
+
+	// Below these speeds the lander is at rest on its legs and the episode is judged
+	RestSpeed = 0.02
+	RestSpin  = 0.001
+
+	// Start position of the lander's center
+	StartX = 390.0
+	StartY = 0.0
+
+	// Playable area: leaving the screen by more than this margin ends the episode
+	BoundsMargin = 100.0
+)
 
-// IsOnLandingPad checks if the lander is positioned over the landing pad
-func IsOnLandingPad(landerCenterX float64) bool {
-	return landerCenterX >= LandingPadLeft && landerCenterX <= LandingPadRight
+// IsOutOfBounds checks if the lander has left the playable area
+func IsOutOfBounds(landerCenterX, landerCenterY float64) bool {
+	return landerCenterX < -BoundsMargin || landerCenterX > ScreenWidth+BoundsMargin ||
+		landerCenterY < -BoundsMargin || landerCenterY >= ScreenHeight+BoundsMargin
 }
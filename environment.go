@@ -3,6 +3,7 @@ package main
 import (
 	"image/color"
 	"math"
+	"sort"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -11,38 +12,45 @@ import (
 // GameState represents the state of the Lunar Lander environment.
 // LanderX and LanderY represent the CENTER point of the lander
 type GameState struct {
-	LanderX    float64
-	LanderY    float64
-	VelocityX  float64
-	VelocityY  float64
-	Angle      float64
-	IsDoneFlag bool
+	LanderX          float64
+	LanderY          float64
+	VelocityX        float64
+	VelocityY        float64
+	Angle            float64
+	AngularVelocity  float64 // Radians per tick, positive is clockwise
+	FuelUsed         float64 // Fuel burnt so far, the tank is empty at FuelCapacity
+	LeftLegOnGround  bool    // The left leg stands on the ground, pressed in by its spring
+	RightLegOnGround bool    // The right leg stands on the ground, pressed in by its spring
+	IsDoneFlag       bool
+	Reason           TerminationReason // Why the episode ended, NotTerminated while flying
+	Terrain          *Environment      `json:"-"` // Ground to land on, nil for flat open ground
 }
 
-// Environment represents the landing environment with peaks.
+// Environment represents the landing environment: a ground line with a flat landing pad, and the wind.
 type Environment struct {
-	Peaks       []Triangle
+	Ground      []Point // Ground surface from left to right, spanning the screen width
 	TargetX     float64 // X-coordinate of the center of the landing pad
 	TargetY     float64 // Y-coordinate of the landing pad
 	TargetWidth float64 // Width of the landing pad
+	Seed        int64   // Seed the level was generated from, unused for the classic map
+	Wind        Wind    // Wind blowing over the level
 }
 
-type Triangle struct {
-	X1, Y1 float64
-	X2, Y2 float64
-	X3, Y3 float64
+// Point is a vertex of the ground line.
+type Point struct {
+	X, Y float64
 }
 
-// NewEnvironment creates a new environment with predefined peaks.
+// NewEnvironment creates the classic map: the landing pad between two pairs of peaks.
 func NewEnvironment() *Environment {
 	return &Environment{
-		Peaks: []Triangle{
+		Ground: []Point{
 			// Left peaks
-			{X1: 0, Y1: GroundLevel, X2: 100, Y2: GroundLevel - 100, X3: 200, Y3: GroundLevel},
-			{X1: 200, Y1: GroundLevel, X2: 250, Y2: GroundLevel - 50, X3: LandingPadLeft, Y3: GroundLevel},
+			{0, GroundLevel}, {100, GroundLevel - 100}, {200, GroundLevel},
+			{250, GroundLevel - 50}, {LandingPadLeft, GroundLevel},
 			// Right peaks
-			{X1: LandingPadRight, Y1: GroundLevel, X2: 550, Y2: GroundLevel - 30, X3: 600, Y3: GroundLevel},
-			{X1: 600, Y1: GroundLevel, X2: 700, Y2: GroundLevel - 50, X3: 800, Y3: GroundLevel},
+			{LandingPadRight, GroundLevel}, {550, GroundLevel - 30}, {600, GroundLevel},
+			{700, GroundLevel - 50}, {800, GroundLevel},
 		},
 		TargetX:     (LandingPadLeft + LandingPadRight) / 2.0,
 		TargetY:     GroundLevel,
@@ -50,77 +58,133 @@ func NewEnvironment() *Environment {
 	}
 }
 
-// Step simulates the environment for a given action and returns the new state.
-func (g *GameState) Step(action int) *GameState {
-	newState := &GameState{
-		LanderX:    g.LanderX,
-		LanderY:    g.LanderY,
-		VelocityX:  g.VelocityX,
-		VelocityY:  g.VelocityY,
-		Angle:      g.Angle,
-		IsDoneFlag: g.IsDoneFlag,
-	}
-
-	switch action {
-	case 0: // Do nothing
-		// Gravity affects the lander
-		newState.VelocityY += Gravity
-	case 1: // Fire left orientation engine
-		newState.Angle -= SideThrust
-	case 2: // Fire main engine
-		newState.VelocityX += math.Sin(newState.Angle) * MainThrust
-		newState.VelocityY -= math.Cos(newState.Angle) * MainThrust
-	case 3: // Fire right orientation engine
-		newState.Angle += SideThrust
-	}
-
-	// Gravity always applies (even when thrusting)
-	newState.VelocityY += Gravity
-	newState.LanderY += newState.VelocityY
-	newState.LanderX += newState.VelocityX
-
-	// Check if the lander has hit the ground
-	if IsLanderOnGround(newState.LanderY) {
-		// Snap to ground level (center point)
-		newState.LanderY = GroundLevel - LanderBottomOffset
-		newState.VelocityY = 0
-		newState.IsDoneFlag = true
+// openGround is flat ground at GroundLevel with the classic pad, for states without a Terrain.
+var openGround = &Environment{
+	Ground:      []Point{{0, GroundLevel}, {ScreenWidth, GroundLevel}},
+	TargetX:     (LandingPadLeft + LandingPadRight) / 2.0,
+	TargetY:     GroundLevel,
+	TargetWidth: LandingPadRight - LandingPadLeft,
+}
+
+// ground returns the ground the lander flies over.
+func (g *GameState) ground() *Environment {
+	if g.Terrain != nil {
+		return g.Terrain
 	}
+	return openGround
+}
 
+// Step simulates the environment for a given action and returns the new state.
+func (g *GameState) Step(action int) *GameState {
+	newState, _ := StepPhysics(g, ActionControls(action))
 	return newState
 }
 
 // Copy creates a deep copy of the current game state.
 func (g *GameState) Copy() *GameState {
 	return &GameState{
-		LanderX:    g.LanderX,
-		LanderY:    g.LanderY,
-		VelocityX:  g.VelocityX,
-		VelocityY:  g.VelocityY,
-		Angle:      g.Angle,
-		IsDoneFlag: g.IsDoneFlag,
+		LanderX:          g.LanderX,
+		LanderY:          g.LanderY,
+		VelocityX:        g.VelocityX,
+		VelocityY:        g.VelocityY,
+		Angle:            g.Angle,
+		AngularVelocity:  g.AngularVelocity,
+		FuelUsed:         g.FuelUsed,
+		LeftLegOnGround:  g.LeftLegOnGround,
+		RightLegOnGround: g.RightLegOnGround,
+		IsDoneFlag:       g.IsDoneFlag,
+		Reason:           g.Reason,
+		Terrain:          g.Terrain,
 	}
 }
 
+// Matches checks if two states describe the same lander, ignoring rounding noise.
+func (g *GameState) Matches(o *GameState) bool {
+	const epsilon = 1e-9
+	return math.Abs(g.LanderX-o.LanderX) < epsilon &&
+		math.Abs(g.LanderY-o.LanderY) < epsilon &&
+		math.Abs(g.VelocityX-o.VelocityX) < epsilon &&
+		math.Abs(g.VelocityY-o.VelocityY) < epsilon &&
+		math.Abs(g.Angle-o.Angle) < epsilon &&
+		math.Abs(g.AngularVelocity-o.AngularVelocity) < epsilon &&
+		math.Abs(g.FuelUsed-o.FuelUsed) < epsilon &&
+		g.LeftLegOnGround == o.LeftLegOnGround &&
+		g.RightLegOnGround == o.RightLegOnGround &&
+		g.IsDoneFlag == o.IsDoneFlag
+}
+
+// Update advances the environment by one tick: the wind gusts change.
 func (e *Environment) Update() {
-	// Placeholder for dynamic updates to the environment
-	// For now, no dynamic updates are needed
+	e.Wind.update()
 }
 
 func (e *Environment) Draw(screen *ebiten.Image) {
-	// Draw the flat landing area
-	ebitenutil.DrawLine(screen, LandingPadLeft, GroundLevel, LandingPadRight, GroundLevel, color.White)
+	// Draw the ground line, including the flat landing area
+	for i := 1; i < len(e.Ground); i++ {
+		ebitenutil.DrawLine(screen, e.Ground[i-1].X, e.Ground[i-1].Y, e.Ground[i].X, e.Ground[i].Y, color.White)
+	}
 
 	// Draw flags at the landing pad boundaries
-	ebitenutil.DrawLine(screen, LandingPadLeft, GroundLevel, LandingPadLeft, GroundLevel-20, color.White)
-	ebitenutil.DrawLine(screen, LandingPadRight, GroundLevel, LandingPadRight, GroundLevel-20, color.White)
+	padLeft, padRight := e.TargetX-e.TargetWidth/2, e.TargetX+e.TargetWidth/2
+	ebitenutil.DrawLine(screen, padLeft, e.TargetY, padLeft, e.TargetY-20, color.White)
+	ebitenutil.DrawLine(screen, padRight, e.TargetY, padRight, e.TargetY-20, color.White)
+}
 
-	// Draw peaks from the environment
-	for _, peak := range e.Peaks {
-		ebitenutil.DrawLine(screen, peak.X1, peak.Y1, peak.X2, peak.Y2, color.White)
-		ebitenutil.DrawLine(screen, peak.X2, peak.Y2, peak.X3, peak.Y3, color.White)
-		ebitenutil.DrawLine(screen, peak.X3, peak.Y3, peak.X1, peak.Y1, color.White)
+// LegPositions returns the screen coordinates of the left and right leg tips,
+// taking the lander's rotation into account.
+func (g *GameState) LegPositions() (leftX, leftY, rightX, rightY float64) {
+	sin, cos := math.Sincos(g.Angle)
+	// Legs sit at (-/+LanderCenterOffsetX, LanderBottomOffset) relative to the center
+	leftX = g.LanderX - LanderCenterOffsetX*cos - LanderBottomOffset*sin
+	leftY = g.LanderY - LanderCenterOffsetX*sin + LanderBottomOffset*cos
+	rightX = g.LanderX + LanderCenterOffsetX*cos - LanderBottomOffset*sin
+	rightY = g.LanderY + LanderCenterOffsetX*sin + LanderBottomOffset*cos
+	return leftX, leftY, rightX, rightY
+}
+
+// LanderShape returns the rotated outline of the lander as convex parts matching landerImage:
+// the body box above the legs, and the left and right legs, each clockwise from the top left.
+func (g *GameState) LanderShape() (body, leftLeg, rightLeg []Point) {
+	const legTop = LanderBottomOffset - LegLength
+	body = g.localBox(-LanderCenterOffsetX+LegWidth, -LanderCenterOffsetY, LanderCenterOffsetX-LegWidth, legTop)
+	leftLeg = g.localBox(-LanderCenterOffsetX, legTop, -LanderCenterOffsetX+LegWidth, LanderBottomOffset)
+	rightLeg = g.localBox(LanderCenterOffsetX-LegWidth, legTop, LanderCenterOffsetX, LanderBottomOffset)
+	return body, leftLeg, rightLeg
+}
+
+// localBox returns the screen coordinates of the corners of a box given relative to the
+// lander's center and rotated with it.
+func (g *GameState) localBox(left, top, right, bottom float64) []Point {
+	sin, cos := math.Sincos(g.Angle)
+	corner := func(x, y float64) Point {
+		return Point{g.LanderX + x*cos - y*sin, g.LanderY + x*sin + y*cos}
 	}
+	return []Point{corner(left, top), corner(right, top), corner(right, bottom), corner(left, bottom)}
+}
+
+// LegContacts reports which legs touch the ground, within LegContactTolerance.
+func (g *GameState) LegContacts() (left, right bool) {
+	env := g.ground()
+	_, leftLeg, rightLeg := g.LanderShape()
+	return env.contact(leftLeg) >= -LegContactTolerance, env.contact(rightLeg) >= -LegContactTolerance
+}
+
+// LegsOnGround counts the legs standing on the ground after the last physics step.
+func (g *GameState) LegsOnGround() int {
+	return boolToBit(g.LeftLegOnGround) + boolToBit(g.RightLegOnGround)
+}
+
+// Fuel returns the fuel left in the tank.
+func (g *GameState) Fuel() float64 {
+	return math.Max(FuelCapacity-g.FuelUsed, 0)
+}
+
+// FuelCutoff returns the engines that can actually fire: none once the tank is empty.
+func (g *GameState) FuelCutoff(c Controls) Controls {
+	if g.Fuel() <= 0 {
+		return Controls{}
+	}
+	return c
 }
 
 // IsDone checks if the game is over.
@@ -128,19 +192,27 @@ func (g *GameState) IsDone() bool {
 	return g.IsDoneFlag
 }
 
-// IsSafeLanding checks if the lander is within safe landing thresholds.
+// IsSafeLanding checks if the lander is within safe landing thresholds on level ground.
 func (g *GameState) IsSafeLanding() bool {
-	// Check if the lander is within safe landing thresholds
+	return g.isSafeTouchdown(0)
+}
+
+// isSafeTouchdown checks the safe landing thresholds on ground tilted by groundAngle.
+// The lander's tilt counts relative to the ground.
+func (g *GameState) isSafeTouchdown(groundAngle float64) bool {
 	return math.Abs(g.VelocityY) <= SafeVerticalSpeed &&
 		math.Abs(g.VelocityX) <= SafeHorizontalSpeed &&
-		math.Abs(g.Angle) <= SafeLandingAngle
+		math.Abs(g.Angle-groundAngle) <= SafeLandingAngle &&
+		math.Abs(g.AngularVelocity) <= SafeAngularSpeed
 }
 
 // CheckLanding determines if the landing is safe or a crash.
 func (g *GameState) CheckLanding() string {
 	// Determine if the landing is safe or a crash
-	if IsLanderOnGround(g.LanderY) {
-		if g.IsSafeLanding() && IsOnLandingPad(g.LanderX) {
+	if left, right := g.LegContacts(); left || right {
+		env := g.ground()
+		leftX, _, rightX, _ := g.LegPositions()
+		if g.IsSafeLanding() && env.IsOnPad(leftX) && env.IsOnPad(rightX) {
 			return "Safe Landing"
 		}
 		return "Crash"
@@ -148,24 +220,144 @@ func (g *GameState) CheckLanding() string {
 	return "In Air"
 }
 
-// CheckCollision checks if a point (x, y) is inside any triangle in the environment.
+// HeightAt returns the Y coordinate of the ground surface at x.
+// Beyond the ends of the ground line the end points' height continues.
+func (e *Environment) HeightAt(x float64) float64 {
+	ground := e.Ground
+	if x <= ground[0].X {
+		return ground[0].Y
+	}
+	if x >= ground[len(ground)-1].X {
+		return ground[len(ground)-1].Y
+	}
+	i := sort.Search(len(ground), func(i int) bool { return ground[i].X >= x })
+	a, b := ground[i-1], ground[i]
+	return a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)
+}
+
+// SlopeAt returns the slope of the ground at x, as the change in Y per unit of X.
+// At a vertex it is the slope of the segment to its left, beyond the ends of the ground line it is flat.
+func (e *Environment) SlopeAt(x float64) float64 {
+	ground := e.Ground
+	if x <= ground[0].X || x > ground[len(ground)-1].X {
+		return 0
+	}
+	i := sort.Search(len(ground), func(i int) bool { return ground[i].X >= x })
+	a, b := ground[i-1], ground[i]
+	return (b.Y - a.Y) / (b.X - a.X)
+}
+
+// IsOnPad checks if x lies over the landing pad.
+func (e *Environment) IsOnPad(x float64) bool {
+	return math.Abs(x-e.TargetX) <= e.TargetWidth/2
+}
+
+// CheckCollision checks if a point (x, y) is below the ground surface.
 func (e *Environment) CheckCollision(x, y float64) bool {
-	// Check if a point (x, y) is inside any triangle
-	for _, peak := range e.Peaks {
-		if pointInTriangle(x, y, peak) {
-			return true
+	return y > e.HeightAt(x)
+}
+
+// contact returns how far the convex hull has sunk into the ground.
+// A negative depth is the hull's clearance above the ground.
+// The ground and the hull's underside are both piecewise linear, so the depth peaks either at
+// a hull vertex or above a ground vertex, such as a peak poking into the hull from below.
+func (e *Environment) contact(hull []Point) float64 {
+	depth := math.Inf(-1)
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, v := range hull {
+		depth = math.Max(depth, v.Y-e.HeightAt(v.X))
+		minX, maxX = math.Min(minX, v.X), math.Max(maxX, v.X)
+	}
+
+	ground := e.Ground
+	for i := sort.Search(len(ground), func(i int) bool { return ground[i].X >= minX }); i < len(ground) && ground[i].X <= maxX; i++ {
+		depth = math.Max(depth, hullBottomAt(hull, ground[i].X)-ground[i].Y)
+	}
+	return depth
+}
+
+// hullBottomAt returns the Y coordinate of the underside of a convex hull at x,
+// which must lie within the hull's X range.
+func hullBottomAt(hull []Point, x float64) float64 {
+	bottom := math.Inf(-1)
+	for i, a := range hull {
+		b := hull[(i+1)%len(hull)]
+		if math.Min(a.X, b.X) > x || math.Max(a.X, b.X) < x {
+			continue
+		}
+		if a.X == b.X {
+			bottom = math.Max(bottom, math.Max(a.Y, b.Y))
+		} else {
+			bottom = math.Max(bottom, a.Y+(b.Y-a.Y)*(x-a.X)/(b.X-a.X))
 		}
 	}
-	return false
+	return bottom
 }
 
-func pointInTriangle(px, py float64, t Triangle) bool {
-	// Barycentric technique to check if a point is inside a triangle
-	area := 0.5 * (-t.Y2*t.X3 + t.Y1*(-t.X2+t.X3) + t.X1*(t.Y2-t.Y3) + t.X2*t.Y3)
-	s := 1 / (2 * area) * (t.Y1*t.X3 - t.X1*t.Y3 + (t.Y3-t.Y1)*px + (t.X1-t.X3)*py)
-	tCoord := 1 / (2 * area) * (t.X1*t.Y2 - t.Y1*t.X2 + (t.Y1-t.Y2)*px + (t.X2-t.X1)*py)
+// settle lets the ground act on the lander after it moved this tick and judges the outcome.
+// The body touching the ground, running into a cliff face or standing a leg on ground steeper
+// than MaxLandingSlope crashes into the terrain, and a leg touching down faster than the safe
+// landing speeds breaks. Otherwise the legs are spring-dampers pushing the lander back out of
+// the ground, tipping it toward the ground's slope, until it comes to rest on both legs: a
+// landing if they stand on the pad with the lander level with the ground, a crash otherwise.
+func (e *Environment) settle(g *GameState) TerminationReason {
+	body, leftLeg, rightLeg := g.LanderShape()
+	if e.contact(body) >= 0 {
+		return CrashedTerrain
+	}
 
-	return s > 0 && tCoord > 0 && (s+tCoord) < 1
+	leftX, _, rightX, _ := g.LegPositions()
+	legs := []struct {
+		depth, x float64
+		onGround *bool
+	}{
+		{e.contact(leftLeg), leftX, &g.LeftLegOnGround},
+		{e.contact(rightLeg), rightX, &g.RightLegOnGround},
+	}
+	// Both legs push off the velocities the lander had when it reached the ground
+	velocityY, angularVelocity := g.VelocityY, g.AngularVelocity
+	for _, leg := range legs {
+		if leg.depth < 0 {
+			*leg.onGround = false
+			continue
+		}
+		arm := leg.x - g.LanderX
+		tipVelocityY := velocityY + angularVelocity*arm
+		if !*leg.onGround {
+			// Legs touch down from above: sinking deeper than the leg fell this tick means the
+			// lander ran into the ground sideways
+			if leg.depth > math.Max(tipVelocityY, 0)+LegContactTolerance {
+				return CrashedTerrain
+			}
+			if tipVelocityY > SafeVerticalSpeed || math.Abs(g.VelocityX) > SafeHorizontalSpeed {
+				return Crashed
+			}
+			*leg.onGround = true
+		}
+		if math.Abs(e.SlopeAt(leg.x)) > MaxLandingSlope {
+			return CrashedTerrain
+		}
+
+		// The leg pushes back along the vertical, never pulling the lander down
+		push := math.Max(LegStiffness*leg.depth+LegDamping*tipVelocityY, 0)
+		g.VelocityY -= push
+		g.AngularVelocity -= push * arm / LanderInertia
+		g.VelocityX *= 1 - LegFriction
+	}
+
+	// At rest the legs hold the lander up against gravity, it will not move on the next tick
+	if !g.LeftLegOnGround || !g.RightLegOnGround || math.Abs(g.VelocityX) > RestSpeed ||
+		math.Abs(g.VelocityY+Gravity) > RestSpeed || math.Abs(g.AngularVelocity) > RestSpin {
+		return NotTerminated
+	}
+	g.VelocityX = 0
+	g.VelocityY = 0
+	g.AngularVelocity = 0
+	groundAngle := math.Atan2(e.HeightAt(rightX)-e.HeightAt(leftX), rightX-leftX)
+	if g.isSafeTouchdown(groundAngle) && e.IsOnPad(leftX) && e.IsOnPad(rightX) {
+		return Landed
+	}
+	return Crashed
 }
 
 // Distance calculates the Euclidean distance from the lander to the center of the landing pad
@@ -40,6 +40,17 @@ func TestIsSafeLanding(t *testing.T) {
 	if gs.IsSafeLanding() {
 		t.Errorf("Expected an unsafe landing, but it was safe")
 	}
+
+	// Test case 3: Unsafe landing (spinning)
+	gs = &GameState{
+		VelocityY:       1.0,
+		VelocityX:       0.5,
+		Angle:           0.1,
+		AngularVelocity: 0.1,
+	}
+	if gs.IsSafeLanding() {
+		t.Errorf("Expected an unsafe landing while spinning, but it was safe")
+	}
 }
 
 func TestCheckLanding(t *testing.T) {
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// ghostAlpha is the opacity of ghost landers, so the live lander stands out.
+const ghostAlpha = 0.4
+
+// ghostColors tint the ghosts in the order they were given.
+var ghostColors = []color.RGBA{
+	{0, 255, 255, 255},
+	{255, 255, 0, 255},
+	{0, 255, 0, 255},
+	{255, 128, 0, 255},
+}
+
+// Ghost is a recorded episode flown alongside the live lander, tick for tick.
+type Ghost struct {
+	Replay *Replay
+	Label  string // Shown next to the ghost, names where the replay came from
+}
+
+// ParseGhost loads a ghost from "FILE" or "LABEL=FILE", labeled by the replay itself without a label.
+// An existing file is taken as it is, so file names may contain "=" too.
+func ParseGhost(arg string) (Ghost, error) {
+	label, filename, labeled := strings.Cut(arg, "=")
+	if _, err := os.Stat(arg); err == nil || !labeled {
+		label, filename, labeled = "", arg, false
+	}
+	replay, err := LoadReplay(filename)
+	if err != nil {
+		return Ghost{}, err
+	}
+	if !labeled {
+		label = replay.Label()
+	}
+	return Ghost{Replay: replay, Label: label}, nil
+}
+
+// Label names who flew the replay: "human" for keyboard flights in the game, otherwise the pilot,
+// with the search settings that tell MCTS runs apart.
+func (r *Replay) Label() string {
+	pilot := r.Header.Pilot
+	if pilot == "game" {
+		pilot = "human"
+		for _, tick := range r.Ticks {
+			if tick.Action >= 0 {
+				pilot = "autopilot" // The MCTS agent flew at least part of the episode
+				break
+			}
+		}
+	}
+	c := r.Header.Config
+	if command := c["agent-command"]; command != "" && (pilot == "process" || pilot == "autopilot") {
+		return command // An external program flew
+	}
+	if pilot != "mcts" && pilot != "autopilot" {
+		return pilot
+	}
+	return fmt.Sprintf("%s (%s iterations, %s rollout)", pilot, c["iterations"], c["rollout"])
+}
+
+// State returns the ghost's state at tick, holding the last state once its episode is over.
+func (g Ghost) State(tick int) *GameState {
+	return g.Replay.State(min(tick, len(g.Replay.Ticks)))
+}
+
+// ghostFlags collects the repeatable -ghost flag.
+type ghostFlags []string
+
+func (f *ghostFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *ghostFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// drawGhosts draws the ghosts at tick as tinted, translucent landers with their labels.
+func drawGhosts(screen *ebiten.Image, ghosts []Ghost, tick int) {
+	for i, ghost := range ghosts {
+		tint := ghostColors[i%len(ghostColors)]
+		state := ghost.State(tick)
+		lander := &Lander{}
+		lander.SetState(state)
+		if tick > 0 && tick <= len(ghost.Replay.Ticks) {
+			c := ghost.Replay.Ticks[tick-1].Controls
+			lander.ThrustDown, lander.ThrustLeft, lander.ThrustRight = boolToBit(c.Main), boolToBit(c.Left), boolToBit(c.Right)
+		}
+
+		var scale ebiten.ColorScale
+		scale.ScaleWithColor(tint)
+		scale.ScaleAlpha(ghostAlpha)
+		lander.DrawScaled(screen, scale)
+
+		label := ghost.Label
+		if state.IsDone() {
+			label += ": " + state.Reason.String()
+		}
+		ebitenutil.DebugPrintAt(screen, label, int(state.LanderX)+20, int(state.LanderY)-25)
+
+		// A swatch in the ghost's color marks its label, since debug text is always white
+		ebitenutil.DrawRect(screen, state.LanderX+14, state.LanderY-20, 4, 4, tint)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayLabel(t *testing.T) {
+	config := map[string]string{"iterations": "200", "rollout": "mixed"}
+	start := &GameState{LanderX: StartX}
+
+	// Test case 1: Flown from the keyboard
+	human := NewReplay(1, "game", config, start)
+	human.Record(-1, Controls{}, 0, 0, start)
+	if label := human.Label(); label != "human" {
+		t.Errorf("Expected a human flight, but got %q", label)
+	}
+
+	// Test case 2: The autopilot took over
+	human.Record(2, Controls{Main: true}, 0, 0, start)
+	if label := human.Label(); label != "autopilot (200 iterations, mixed rollout)" {
+		t.Errorf("Expected an autopilot flight with its settings, but got %q", label)
+	}
+
+	// Test case 3: Headless runs
+	if label := NewReplay(1, "mcts", config, start).Label(); label != "mcts (200 iterations, mixed rollout)" {
+		t.Errorf("Expected MCTS with its settings, but got %q", label)
+	}
+	if label := NewReplay(1, "heuristic", config, start).Label(); label != "heuristic" {
+		t.Errorf("Expected the pilot's name, but got %q", label)
+	}
+
+	// Test case 4: External programs go by their command
+	config["agent-command"] = "python3 bot.py"
+	if label := NewReplay(1, "process", config, start).Label(); label != "python3 bot.py" {
+		t.Errorf("Expected the program's command, but got %q", label)
+	}
+}
+
+func TestGhost(t *testing.T) {
+	start := StartDistribution{}.Sample(0, NewEnvironment())
+	replay := NewReplay(0, "idle", nil, start)
+	RecordEpisode(PilotFunc(func(*GameState) int { return 0 }), start, 1000, replay)
+	filename, err := SaveReplay(t.TempDir(), replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test case 1: Labels from the argument or the replay
+	ghost, err := ParseGhost("config A=" + filename)
+	if err != nil || ghost.Label != "config A" {
+		t.Fatalf("Expected the label config A, but got %q (%v)", ghost.Label, err)
+	}
+	if ghost, err := ParseGhost(filename); err != nil || ghost.Label != "idle" {
+		t.Errorf("Expected the label idle, but got %q (%v)", ghost.Label, err)
+	}
+	// File names with "=" in them, labeled or not
+	dir := filepath.Join(t.TempDir(), "lr=0.1")
+	equals, err := SaveReplay(dir, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ghost, err := ParseGhost(equals); err != nil || ghost.Label != "idle" {
+		t.Errorf("Expected the whole argument as the file, but got %q (%v)", ghost.Label, err)
+	}
+	if ghost, err := ParseGhost("run=" + equals); err != nil || ghost.Label != "run" {
+		t.Errorf("Expected the label run, but got %q (%v)", ghost.Label, err)
+	}
+	if _, err := ParseGhost(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Errorf("Expected an error for a missing replay")
+	}
+
+	// Test case 2: Synchronized by tick, resting on its last state once it is over
+	if got := ghost.State(10); !got.Matches(replay.State(10)) {
+		t.Errorf("Expected the state of tick 10, but got %+v", got)
+	}
+	end := len(replay.Ticks)
+	if got := ghost.State(end + 100); !got.IsDone() || !got.Matches(replay.State(end)) {
+		t.Errorf("Expected the ghost to stay where its episode ended, but got %+v", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Env is the Gymnasium-style contract for agents and tooling: Reset starts an episode from a
+// seed, Step takes one of the action space's actions and reports what followed as an
+// observation vector. Reset must be called before the first Step.
+type Env interface {
+	Reset(seed int64) (Observation, Info)
+	Step(action int) (obs Observation, reward float64, terminated, truncated bool, info Info)
+	ActionSpace() Discrete
+	ObservationSpace() Box
+}
+
+// Observation is the README's 8-dimensional state vector, in Gym's frame: measured from the
+// resting spot on the landing pad in units of half the screen width, with y and its velocity
+// pointing up, speeds in units of 10 pixels per tick, the angle in radians from -Pi to Pi,
+// positive clockwise, the angular velocity in radians per tick, and 1 for each leg on the ground.
+type Observation [8]float64
+
+// Indices into an Observation
+const (
+	ObsX = iota
+	ObsY
+	ObsVelocityX
+	ObsVelocityY
+	ObsAngle
+	ObsAngularVelocity
+	ObsLeftLeg
+	ObsRightLeg
+)
+
+// Info carries what an observation leaves out.
+type Info struct {
+	Seed   int64             `json:"seed"`   // Seed the episode was reset with
+	Tick   int               `json:"tick"`   // Ticks since the reset
+	Reason TerminationReason `json:"reason"` // Why the episode ended, NotTerminated while flying
+	Fuel   float64           `json:"fuel"`   // Fuel left in the tank
+}
+
+// Discrete is a space of N actions, numbered 0 to N-1.
+type Discrete struct {
+	N int `json:"n"`
+}
+
+// Contains checks if action is in the space.
+func (d Discrete) Contains(action int) bool {
+	return action >= 0 && action < d.N
+}
+
+// Box is a space of vectors lying between Low and High in every dimension.
+type Box struct {
+	Low  []float64 `json:"low"`
+	High []float64 `json:"high"`
+}
+
+// Contains checks if v has the space's dimensions and lies within its bounds.
+func (b Box) Contains(v []float64) bool {
+	if len(v) != len(b.Low) {
+		return false
+	}
+	for i, x := range v {
+		if x < b.Low[i] || x > b.High[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Observation converts the state into the observation vector.
+func (g *GameState) Observation() Observation {
+	padX, restY := padCenter(g)
+	return Observation{
+		ObsX:               (g.LanderX - padX) / distanceUnit,
+		ObsY:               (restY - g.LanderY) / distanceUnit,
+		ObsVelocityX:       g.VelocityX / speedUnit,
+		ObsVelocityY:       -g.VelocityY / speedUnit,
+		ObsAngle:           math.Remainder(g.Angle, 2*math.Pi),
+		ObsAngularVelocity: g.AngularVelocity,
+		ObsLeftLeg:         float64(boolToBit(g.LeftLegOnGround)),
+		ObsRightLeg:        float64(boolToBit(g.RightLegOnGround)),
+	}
+}
+
+// LanderEnv is the lunar lander as an Env, playing episodes like the headless runner.
+type LanderEnv struct {
+	Level    func(seed int64) *Environment // Builds the level for a seed, the classic map when nil
+	Start    StartDistribution             // Where episodes start
+	MaxTicks int                           // Ticks before an episode is truncated, 0 for no limit
+	Reward   Reward                        // Scores the ticks, ScoreReward when nil
+
+	state     *GameState
+	info      Info
+	truncated bool
+}
+
+// NewLanderEnv creates an environment on the classic map with the runner's defaults.
+func NewLanderEnv() *LanderEnv {
+	return &LanderEnv{Start: DefaultStartDistribution(), MaxTicks: 1000}
+}
+
+// Reset starts a new episode: the level, the start state and the wind are all drawn from seed.
+func (e *LanderEnv) Reset(seed int64) (Observation, Info) {
+	level := NewEnvironment()
+	if e.Level != nil {
+		level = e.Level(seed)
+	}
+	e.state = e.Start.Sample(seed, level)
+	e.truncated = false
+	e.info = Info{Seed: seed, Fuel: e.state.Fuel()}
+	return e.state.Observation(), e.info
+}
+
+// Step advances the episode by one tick. Once the episode has terminated or been truncated,
+// Step leaves it as it is and rewards nothing. Actions outside the action space panic.
+func (e *LanderEnv) Step(action int) (Observation, float64, bool, bool, Info) {
+	if !e.ActionSpace().Contains(action) {
+		panic(fmt.Sprintf("action %d outside the action space", action))
+	}
+	if e.state.IsDone() || e.truncated {
+		return e.state.Observation(), 0, e.state.IsDone(), e.truncated, e.info
+	}
+
+	reward := e.Reward
+	if reward == nil {
+		reward = ScoreReward
+	}
+	e.state.ground().Update()
+	next := e.state.Step(action)
+	r := reward.Reward(e.state, next, ActionControls(action))
+	e.state = next
+
+	e.info.Tick++
+	e.info.Reason = next.Reason
+	e.info.Fuel = next.Fuel()
+	e.truncated = !next.IsDone() && e.MaxTicks > 0 && e.info.Tick >= e.MaxTicks
+	return next.Observation(), r, next.IsDone(), e.truncated, e.info
+}
+
+// Observe returns the current observation and info without advancing the episode.
+func (e *LanderEnv) Observe() (Observation, Info) {
+	return e.state.Observation(), e.info
+}
+
+// State returns a copy of the current state, for pilots that plan on the full simulation.
+func (e *LanderEnv) State() *GameState {
+	return e.state.Copy()
+}
+
+// ActionSpace is the README's four actions: nothing, left, main and right engine.
+func (e *LanderEnv) ActionSpace() Discrete {
+	return Discrete{N: 4}
+}
+
+// ObservationSpace bounds every observation: positions within the playable area,
+// speeds and spins well beyond what the engines and gravity reach.
+func (e *LanderEnv) ObservationSpace() Box {
+	return Box{
+		Low:  []float64{-2.5, -2.5, -5, -5, -math.Pi, -1, 0, 0},
+		High: []float64{2.5, 2.5, 5, 5, math.Pi, 1, 1, 1},
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestObservation(t *testing.T) {
+	// Test case 1: Resting on the pad center is the origin
+	env := NewEnvironment()
+	padX, restY := padCenter(&GameState{Terrain: env})
+	obs := (&GameState{LanderX: padX, LanderY: restY, LeftLegOnGround: true, RightLegOnGround: true, Terrain: env}).Observation()
+	if obs != (Observation{ObsLeftLeg: 1, ObsRightLeg: 1}) {
+		t.Errorf("Expected the origin with both legs down, but got %v", obs)
+	}
+
+	// Test case 2: Up and to the left, falling and spun around more than a full turn
+	obs = (&GameState{LanderX: padX - 200, LanderY: restY - 400, VelocityY: 5, Angle: 2*math.Pi + 0.1, Terrain: env}).Observation()
+	if obs[ObsX] != -0.5 || obs[ObsY] != 1 || obs[ObsVelocityY] != -0.5 || math.Abs(obs[ObsAngle]-0.1) > 1e-9 {
+		t.Errorf("Expected (-0.5, 1) falling at 0.5 tilted 0.1, but got %v", obs)
+	}
+}
+
+func TestLanderEnv(t *testing.T) {
+	var env Env = NewLanderEnv()
+	space := env.ObservationSpace()
+
+	// Test case 1: Resets with the same seed start the same way
+	first, info := env.Reset(3)
+	again, _ := env.Reset(3)
+	other, _ := env.Reset(4)
+	if first != again || first == other || info.Seed != 3 || info.Tick != 0 || info.Fuel != FuelCapacity {
+		t.Errorf("Expected seeded starts, but got %v, %v and %v with %+v", first, again, other, info)
+	}
+	if !space.Contains(first[:]) {
+		t.Errorf("Expected the start %v inside the observation space", first)
+	}
+
+	// Test case 2: Doing nothing falls until the episode terminates, scored like the runner
+	env.Reset(0)
+	score, ticks := 0.0, 0
+	for {
+		obs, reward, terminated, truncated, info := env.Step(0)
+		score += reward
+		ticks++
+		if !space.Contains(obs[:]) {
+			t.Fatalf("Tick %d: observation %v outside the observation space", ticks, obs)
+		}
+		if truncated {
+			t.Fatalf("Expected to crash before the tick limit, but got %+v", info)
+		}
+		if terminated {
+			if info.Reason != Crashed || info.Tick != ticks {
+				t.Errorf("Expected a crash after %d ticks, but got %+v", ticks, info)
+			}
+			break
+		}
+	}
+	want := RunEpisode(PilotFunc(func(*GameState) int { return 0 }), DefaultStartDistribution().Sample(0, NewEnvironment()), 1000)
+	if score != want.Score || ticks != want.Ticks {
+		t.Errorf("Expected %d ticks scoring %v as in the runner, but got %d scoring %v", want.Ticks, want.Score, ticks, score)
+	}
+
+	// Test case 3: Stepping a finished episode changes nothing
+	if _, reward, terminated, _, info := env.Step(2); reward != 0 || !terminated || info.Tick != ticks {
+		t.Errorf("Expected the finished episode to stay put, but got reward %v and %+v", reward, info)
+	}
+}
+
+func TestLanderEnvTruncates(t *testing.T) {
+	env := NewLanderEnv()
+	env.MaxTicks = 5
+	env.Level = func(seed int64) *Environment {
+		level, _ := GenerateTerrain(DefaultTerrainConfig(seed)) // The default pad fits
+		return level
+	}
+	env.Reset(1)
+	for i := 1; i <= 5; i++ {
+		_, _, terminated, truncated, _ := env.Step(2)
+		if terminated || truncated != (i == 5) {
+			t.Fatalf("Tick %d: expected truncation on tick 5 only, but got terminated %v, truncated %v", i, terminated, truncated)
+		}
+	}
+	if env.State().Terrain.Seed != 1 {
+		t.Errorf("Expected the level generated from the seed")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for an action outside the action space")
+		}
+	}()
+	env.Step(4)
+}
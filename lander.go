@@ -2,7 +2,6 @@ package main
 
 import (
 	"image/color"
-	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -24,74 +23,117 @@ type Lander struct {
 	VelocityX        float64
 	VelocityY        float64
 	Angle            float64
+	AngularVelocity  float64
+	FuelUsed         float64
 	ThrustDown       int
 	ThrustLeft       int
 	ThrustRight      int
 	LeftLegOnGround  bool
 	RightLegOnGround bool
 	Crashed          bool
+	Outcome          TerminationReason // Why the lander's episode ended, NotTerminated while flying
 }
 
-func (l *Lander) Update(env *Environment) {
-	// Up arrow key for main thrust
-	if ebiten.IsKeyPressed(ebiten.KeyUp) {
-		l.ThrustDown = 1
-		l.VelocityX += math.Sin(l.Angle) * MainThrust
-		l.VelocityY += math.Cos(l.Angle) * -MainThrust
-	} else {
-		l.ThrustDown = 0
-	}
+// Update reads the keyboard and advances the lander by one tick.
+func (l *Lander) Update(env *Environment) TerminationReason {
+	return l.Apply(KeyboardControls(), env)
+}
 
-	// Left arrow key for left orientation engine
-	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		l.ThrustLeft = 1
-		l.Angle -= SideThrust
-	} else {
-		l.ThrustLeft = 0
+// KeyboardControls reads the arrow keys into engine controls.
+func KeyboardControls() Controls {
+	return Controls{
+		Main:  ebiten.IsKeyPressed(ebiten.KeyUp),    // Up arrow key for main thrust
+		Left:  ebiten.IsKeyPressed(ebiten.KeyLeft),  // Left arrow key for left orientation engine
+		Right: ebiten.IsKeyPressed(ebiten.KeyRight), // Right arrow key for right orientation engine
 	}
+}
+
+// Apply advances the lander by one tick of the shared physics step.
+func (l *Lander) Apply(c Controls, env *Environment) TerminationReason {
+	state := l.State()
+	state.Terrain = env
+
+	// Only show flames for engines that have fuel
+	c = state.FuelCutoff(c)
+	l.ThrustDown = boolToBit(c.Main)
+	l.ThrustLeft = boolToBit(c.Left)
+	l.ThrustRight = boolToBit(c.Right)
+
+	next, reason := StepPhysics(state, c)
+	l.SetState(next)
+	l.Crashed = reason == Crashed || reason == CrashedTerrain
+	return reason
+}
 
-	// Right arrow key for right orientation engine
-	if ebiten.IsKeyPressed(ebiten.KeyRight) {
-		l.ThrustRight = 1
-		l.Angle += SideThrust
-	} else {
-		l.ThrustRight = 0
+// State converts the lander into a simulation state.
+func (l *Lander) State() *GameState {
+	return &GameState{
+		LanderX:          l.X,
+		LanderY:          l.Y,
+		VelocityX:        l.VelocityX,
+		VelocityY:        l.VelocityY,
+		Angle:            l.Angle,
+		AngularVelocity:  l.AngularVelocity,
+		FuelUsed:         l.FuelUsed,
+		LeftLegOnGround:  l.LeftLegOnGround,
+		RightLegOnGround: l.RightLegOnGround,
+		IsDoneFlag:       l.Outcome != NotTerminated,
+		Reason:           l.Outcome,
 	}
+}
 
-	// Update lander position and velocity
-	l.VelocityY += Gravity // Gravity
-	l.X += l.VelocityX
-	l.Y += l.VelocityY
+// SetState copies a simulation state onto the lander.
+func (l *Lander) SetState(s *GameState) {
+	l.X = s.LanderX
+	l.Y = s.LanderY
+	l.VelocityX = s.VelocityX
+	l.VelocityY = s.VelocityY
+	l.Angle = s.Angle
+	l.AngularVelocity = s.AngularVelocity
+	l.FuelUsed = s.FuelUsed
+	l.LeftLegOnGround = s.LeftLegOnGround
+	l.RightLegOnGround = s.RightLegOnGround
+	l.Outcome = s.Reason
+}
 
-	// Check for collisions with the environment
-	if env.CheckCollision(l.X-15, l.Y+20) || env.CheckCollision(l.X+15, l.Y+20) {
-		l.Crashed = true
-	}
+// Controls returns the engines fired on the last tick.
+func (l *Lander) Controls() Controls {
+	return Controls{Main: l.ThrustDown > 0, Left: l.ThrustLeft > 0, Right: l.ThrustRight > 0}
 }
 
 // SafeToLand checks if the lander's speed and angle are within safe landing parameters
 func (l *Lander) SafeToLand() bool {
-	return math.Abs(l.VelocityY) <= SafeVerticalSpeed &&
-		math.Abs(l.VelocityX) <= SafeHorizontalSpeed &&
-		math.Abs(l.Angle) <= SafeLandingAngle
+	return l.State().IsSafeLanding()
+}
+
+func boolToBit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
 }
 
 func (l *Lander) Draw(screen *ebiten.Image) {
+	l.DrawScaled(screen, ebiten.ColorScale{})
+}
+
+// DrawScaled draws the lander with its colors scaled, e.g. tinted and translucent for a ghost.
+func (l *Lander) DrawScaled(screen *ebiten.Image, scale ebiten.ColorScale) {
 	// Draw the lander body
-	op := &ebiten.DrawImageOptions{}
+	op := &ebiten.DrawImageOptions{ColorScale: scale}
 	op.GeoM.Translate(-15, -15)
 	op.GeoM.Rotate(l.Angle)
 	op.GeoM.Translate(l.X, l.Y)
 	screen.DrawImage(landerImage, op)
 
 	// Draw thrust flames if active
-	l.drawThrustFlames(screen)
+	l.drawThrustFlames(screen, scale)
 }
 
-func (l *Lander) drawThrustFlames(screen *ebiten.Image) {
+func (l *Lander) drawThrustFlames(screen *ebiten.Image, scale ebiten.ColorScale) {
 	if l.ThrustDown > 0 {
 		// Draw flame for main thrust
-		op := &ebiten.DrawImageOptions{}
+		op := &ebiten.DrawImageOptions{ColorScale: scale}
 		flameImage := ebiten.NewImage(6, 10)
 		flameImage.Fill(color.RGBA{255, 165, 0, 255})
 		op.GeoM.Translate(12, 20)   // position relative to lander image
@@ -102,7 +144,7 @@ func (l *Lander) drawThrustFlames(screen *ebiten.Image) {
 	}
 	if l.ThrustLeft > 0 {
 		// Draw flame for left thrust
-		op := &ebiten.DrawImageOptions{}
+		op := &ebiten.DrawImageOptions{ColorScale: scale}
 		flameImage := ebiten.NewImage(10, 4)
 		flameImage.Fill(color.RGBA{255, 165, 0, 255})
 		op.GeoM.Translate(25, 8)    // position relative to lander image
@@ -113,7 +155,7 @@ func (l *Lander) drawThrustFlames(screen *ebiten.Image) {
 	}
 	if l.ThrustRight > 0 {
 		// Draw flame for right thrust
-		op := &ebiten.DrawImageOptions{}
+		op := &ebiten.DrawImageOptions{ColorScale: scale}
 		flameImage := ebiten.NewImage(10, 4)
 		flameImage.Fill(color.RGBA{255, 165, 0, 255})
 		op.GeoM.Translate(-5, 8)    // position relative to lander image
@@ -123,27 +165,3 @@ func (l *Lander) drawThrustFlames(screen *ebiten.Image) {
 		screen.DrawImage(flameImage, op)
 	}
 }
-
-func (l *Lander) checkCollisionWithPeaks(peaks []float64) {
-	// Reset leg states
-	l.LeftLegOnGround = false
-	l.RightLegOnGround = false
-
-	landerLeftX := l.X - 15  // Left edge of the lander
-	landerRightX := l.X + 15 // Right edge of the lander
-
-	for x, peakY := range peaks {
-		if float64(x) >= landerLeftX && float64(x) <= landerRightX {
-			if l.Y+20 >= peakY { // Check if legs are touching the peak
-				if float64(x) < l.X {
-					l.LeftLegOnGround = true
-				} else {
-					l.RightLegOnGround = true
-				}
-
-				// If either leg touches the peak, the lander crashes
-				l.Crashed = true
-			}
-		}
-	}
-}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestLanderApplyLegContacts(t *testing.T) {
+	env := NewEnvironment()
+
+	// Tilted clockwise onto the pad: the right leg touches first
+	l := &Lander{X: 400, Y: 481.75, VelocityY: 1.0, Angle: 0.2}
+	if reason := l.Apply(Controls{}, env); reason != NotTerminated {
+		t.Fatalf("Expected to keep settling, but got '%s'", reason)
+	}
+	if l.LeftLegOnGround || !l.RightLegOnGround {
+		t.Errorf("Expected only the right leg down, but got %+v", l)
+	}
+
+	// Then tips onto both legs and comes to rest
+	reason := NotTerminated
+	for i := 0; i < 200 && reason == NotTerminated; i++ {
+		reason = l.Apply(Controls{}, env)
+	}
+	if reason != Landed || !l.LeftLegOnGround || !l.RightLegOnGround || l.Crashed {
+		t.Errorf("Expected to land on both legs, but got '%s' with %+v", reason, l)
+	}
+
+	// Straight down too fast: the legs break
+	l = &Lander{X: 400, Y: 484, VelocityY: 3.0}
+	if reason := l.Apply(Controls{}, env); reason != Crashed || !l.Crashed {
+		t.Errorf("Expected to crash, but got '%s' with %+v", reason, l)
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image/color"
 	"image/png"
 	"log"
 	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -14,7 +16,8 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-var Env *Environment
+// World is the level played in the window.
+var World *Environment
 
 type Game struct {
 	Lander              *Lander
@@ -25,9 +28,19 @@ type Game struct {
 	won                 bool
 	paused              bool
 	Score               float64
-	prevDistance        float64 // Track previous distance for reward calculation
-	prevSpeed           float64 // Track previous speed for reward calculation
-	hasLanded           bool    // Track if legs have touched ground this episode
+	outcome             TerminationReason // Why the last episode ended
+	autopilot           bool              // Let the MCTS agent or an external program fly instead of the keyboard
+	pilot               Pilot             // Autopilot, keeps its search tree or program between ticks of an episode
+	agentConfig         AgentConfig       // Search settings for the autopilot
+	process             ProcessConfig     // External program flying as the autopilot, none to search
+	lastAction          int               // Action chosen by the agent on the last autopilot tick
+	start               StartDistribution // Where new episodes start
+	level               int64             // Seed of the level, episodes are seeded with level + episode
+	episode             int               // Episodes played so far
+	recordDir           string            // Directory to save a replay of every episode to, empty to not record
+	recording           *Replay           // Replay of the current episode while recording
+	config              map[string]string // Flags the game was started with, stored in replays
+	ghosts              []Ghost           // Recorded episodes flown alongside the lander
 }
 
 func (g *Game) Update() error {
@@ -41,56 +54,94 @@ func (g *Game) Update() error {
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.saveRecording()
+		g.closePilot()
 		return ebiten.Termination
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
 		g.screenshotRequested = true
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.autopilot = !g.autopilot
+	}
 
 	// Update game state
-	Env.Update()
-	g.Lander.Update(Env)
+	World.Update()
+	prev := g.Lander.State()
+	prev.Terrain = World
+	var reason TerminationReason
+	if g.autopilot {
+		g.lastAction = g.autopilotAction()
+		reason = g.Lander.Apply(ActionControls(g.lastAction), World)
+	} else {
+		reason = g.Lander.Update(World)
+	}
 	g.TickElapsed++
 
+	next := g.Lander.State()
+	next.Terrain = World
+	reward := ScoreReward.Reward(prev, next, g.Lander.Controls())
+	g.Score += reward
+	if g.recording != nil {
+		action := -1
+		if g.autopilot {
+			action = g.lastAction
+		}
+		g.recording.Record(action, g.Lander.Controls(), World.Wind.Force(), reward, next)
+	}
+
 	// Check for landing/crash
-	if g.Lander.Crashed && !g.paused {
+	switch reason {
+	case Landed:
+		g.won = true
+	case Crashed, CrashedTerrain, OutOfBounds:
 		g.crashed = true
+	}
+	if reason != NotTerminated {
 		g.paused = true
-		g.Score -= 100
+		g.outcome = reason
 		g.Lander.VelocityX = 0
 		g.Lander.VelocityY = 0
-		return nil
-	}
-
-	// Check if it's on the landing pad
-	onPad := IsOnLandingPad(g.Lander.X)
-
-	// Check if landing conditions are safe
-	safe := g.Lander.SafeToLand()
-
-	if IsLanderOnGround(g.Lander.Y) {
-		if safe && onPad {
-			// Safe landing!
-			g.won = true
-			g.paused = true
-			g.Score += 100
-			g.Lander.VelocityX = 0
-			g.Lander.VelocityY = 0
-		} else {
-			// Crash - either too fast, wrong angle, or off pad
-			g.crashed = true
-			g.paused = true
-			g.Score -= 100
-			g.Lander.VelocityX = 0
-			g.Lander.VelocityY = 0
-		}
+		g.saveRecording()
 	}
 
 	return nil
 }
 
+// autopilotAction plans from the lander's current state and returns the agent's action.
+func (g *Game) autopilotAction() int {
+	state := g.Lander.State()
+	state.Terrain = World
+	if g.pilot == nil && len(g.process.Command) > 0 {
+		pilot, err := StartProcessPilot(g.process, g.seed())
+		if err != nil {
+			log.Print(err)
+			g.autopilot = false
+			return 0
+		}
+		g.pilot = pilot
+	} else if g.pilot == nil {
+		config := g.agentConfig
+		config.Seed = g.seed()
+		g.pilot = &MCTSPilot{Config: config}
+	}
+	return g.pilot.Action(state)
+}
+
+// closePilot ends the autopilot's program, if one is flying, reporting why it failed.
+func (g *Game) closePilot() {
+	if process, ok := g.pilot.(*ProcessPilot); ok {
+		if err := process.Close(); err != nil {
+			log.Printf("Episode %d: %v", g.episode, err)
+		}
+	}
+	g.pilot = nil
+}
+
 func (g *Game) handlePausedInput() error {
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.saveRecording()
+		g.closePilot()
 		return ebiten.Termination
 	}
 
@@ -100,15 +151,12 @@ func (g *Game) handlePausedInput() error {
 			g.paused = false
 			g.crashed = false
 			g.won = false
-			g.Lander = &Lander{X: 390, Y: 0}
+			g.outcome = NotTerminated
+			g.closePilot()
+			g.episode++
+			g.Lander = g.startLander()
 			g.TickElapsed = 0
 			g.Score = 0
-			g.hasLanded = false
-			// Initialize distance and speed tracking
-			targetX := (LandingPadLeft + LandingPadRight) / 2.0
-			targetY := GroundLevel - LanderBottomOffset
-			g.prevDistance = math.Sqrt(math.Pow(g.Lander.X-targetX, 2) + math.Pow(g.Lander.Y-targetY, 2))
-			g.prevSpeed = 0
 		}
 	} else {
 		// If manually paused, only space unpauses
@@ -119,30 +167,75 @@ func (g *Game) handlePausedInput() error {
 	return nil
 }
 
-func (g *Game) checkOffScreen() bool {
-	return g.Lander.X < -100 || g.Lander.X > 900 || g.Lander.Y >= 700 || g.Lander.Y < -100
+// seed is the seed of the current episode, drawing its start, gusts and autopilot searches.
+func (g *Game) seed() int64 {
+	return g.level + int64(g.episode)
+}
+
+// startLander places a new lander at the start of the current episode and calms the wind,
+// so an episode flown the same way plays out the same.
+func (g *Game) startLander() *Lander {
+	World.Wind.reseed(g.seed())
+	start := g.start.Sample(g.seed(), World)
+	if g.recordDir != "" {
+		g.recording = NewReplay(g.seed(), "game", g.config, start)
+	}
+	lander := &Lander{}
+	lander.SetState(start)
+	return lander
+}
+
+// saveRecording saves the replay of the current episode, even if it was cut short, and stops recording.
+func (g *Game) saveRecording() {
+	if g.recording == nil || len(g.recording.Ticks) == 0 {
+		return
+	}
+	if filename, err := SaveReplay(g.recordDir, g.recording); err != nil {
+		log.Printf("Saving the replay failed: %v", err)
+	} else {
+		log.Printf("Saved the replay to %s", filename)
+	}
+	g.recording = nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.Fill(color.Black)
-	Env.Draw(screen)
+	World.Draw(screen)
+	drawGhosts(screen, g.ghosts, g.TickElapsed)
 	g.Lander.Draw(screen)
 
 	// draw thrust as bits, not booleans
+	pilot := "Manual"
+	if p, ok := g.pilot.(*MCTSPilot); ok && g.autopilot {
+		pilot = fmt.Sprintf("Autopilot (action %d, %d sims in %v, reused %d visits)",
+			g.lastAction, p.LastSearch.Simulations, p.LastSearch.Elapsed.Round(time.Millisecond), p.LastReuse.RetainedVisits)
+	} else if p, ok := g.pilot.(*ProcessPilot); ok && g.autopilot {
+		pilot = fmt.Sprintf("%s (action %d, %d missed moves)", strings.Join(g.process.Command, " "), g.lastAction, p.MissedMoves)
+	}
 	msg := fmt.Sprintf(
-		"X: %4.2f, Y: %4.2f\nVelX: %4.2f, VelY: %4.2f\nAngle: %4.2f\nThrust: D:%d L:%d R:%d\nTick: %d/%d\nScore: %4.2f",
-		g.Lander.X, g.Lander.Y, g.Lander.VelocityX, g.Lander.VelocityY, g.Lander.Angle,
-		g.Lander.ThrustDown, g.Lander.ThrustLeft, g.Lander.ThrustRight, g.TickElapsed, g.TickLimit, g.Score,
+		"X: %4.2f, Y: %4.2f\nVelX: %4.2f, VelY: %4.2f\nAngle: %4.2f, AngVel: %4.3f\nThrust: D:%d L:%d R:%d, Legs: L:%d R:%d\nPilot: %s\nTick: %d/%d\nScore: %4.2f",
+		g.Lander.X, g.Lander.Y, g.Lander.VelocityX, g.Lander.VelocityY, g.Lander.Angle, g.Lander.AngularVelocity,
+		g.Lander.ThrustDown, g.Lander.ThrustLeft, g.Lander.ThrustRight,
+		boolToBit(g.Lander.LeftLegOnGround), boolToBit(g.Lander.RightLegOnGround), pilot, g.TickElapsed, g.TickLimit, g.Score,
 	)
-	ebitenutil.DebugPrintAt(screen, msg, 0, 500)
+	ebitenutil.DebugPrintAt(screen, msg, 0, 484)
+	drawFuelGauge(screen, g.Lander.State().Fuel()/FuelCapacity)
+	drawWindIndicator(screen, World.Wind.Force())
 
-	if g.crashed {
+	if g.outcome == OutOfBounds {
+		ebitenutil.DebugPrintAt(screen, "Out of Bounds", 350, 300)
+	} else if g.outcome == CrashedTerrain {
+		ebitenutil.DebugPrintAt(screen, "You Crashed into the Mountains", 300, 300)
+	} else if g.crashed {
 		ebitenutil.DebugPrintAt(screen, "You Crashed", 350, 300)
 	} else if g.won {
 		ebitenutil.DebugPrintAt(screen, "You Won", 350, 300)
 	} else if g.paused {
 		ebitenutil.DebugPrintAt(screen, "Paused", 350, 300)
 	}
+	if g.won || g.crashed {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Fuel used: %.1f", g.Lander.FuelUsed), 350, 316)
+	}
 
 	if g.screenshotRequested {
 		g.screenshotRequested = false
@@ -151,6 +244,38 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 }
 
+// drawFuelGauge draws the share of fuel left as a bar in the top right corner.
+func drawFuelGauge(screen *ebiten.Image, fuel float64) {
+	const x, y, width, height = ScreenWidth - 110, 10, 100, 10
+
+	gaugeColor := color.RGBA{0, 200, 0, 255}
+	if fuel < 0.2 {
+		gaugeColor = color.RGBA{200, 0, 0, 255}
+	}
+	ebitenutil.DebugPrintAt(screen, "Fuel", x-35, y-3)
+	ebitenutil.DrawRect(screen, x, y, width, height, color.Gray{64})
+	ebitenutil.DrawRect(screen, x, y, width*fuel, height, gaugeColor)
+}
+
+// drawWindIndicator draws the wind force below the fuel gauge as an arrow from the middle,
+// pointing the way it blows and longer the stronger it is.
+func drawWindIndicator(screen *ebiten.Image, force float64) {
+	const x, y, width = ScreenWidth - 110, 30, 100
+	const scale = 2500 // Pixels of arrow per unit of force, a wind of 0.02 fills half the width
+	length := math.Max(-width/2, math.Min(width/2, force*scale))
+
+	ebitenutil.DebugPrintAt(screen, "Wind", x-35, y-8)
+	center := float64(x + width/2)
+	ebitenutil.DrawLine(screen, center, y-4, center, y+4, color.Gray{64})
+	if length != 0 {
+		tip := center + length
+		head := math.Copysign(4, -length)
+		ebitenutil.DrawLine(screen, center, y, tip, y, color.White)
+		ebitenutil.DrawLine(screen, tip, y, tip+head, y-3, color.White)
+		ebitenutil.DrawLine(screen, tip, y, tip+head, y+3, color.White)
+	}
+}
+
 func (g *Game) saveScreenshot(screen *ebiten.Image) {
 	filename := time.Now().Format("2006.01.02_15.04.05") + ".png"
 	file, err := os.Create(filename)
@@ -169,22 +294,79 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		var command func([]string) error
+		switch os.Args[1] {
+		case "run":
+			command = runCommand
+		case "tree":
+			command = treeCommand
+		case "serve":
+			command = serveCommand
+		}
+		if command != nil {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	autopilot := flag.Bool("autopilot", false, "let the MCTS agent, or the -agent-command program, fly the lander (toggle in game with A)")
+	autopilotConfig := agentFlags(flag.CommandLine, AutopilotAgentConfig())
+	processConfig := processFlags(flag.CommandLine)
+	level := terrainFlags(flag.CommandLine)
+	startDistribution := startFlags(flag.CommandLine)
+	levelSeed := flag.Int64("level", 1, "seed of the random terrain and the start states")
+	record := flag.String("record", "", "directory to save a replay of every episode to")
+	replayFile := flag.String("replay", "", "play back a replay file instead of flying")
+	var ghostFiles ghostFlags
+	flag.Var(&ghostFiles, "ghost", "fly a replay file alongside as a ghost, as FILE or LABEL=FILE; repeatable")
+	flag.Parse()
+
+	ghosts := make([]Ghost, 0, len(ghostFiles))
+	for _, arg := range ghostFiles {
+		ghost, err := ParseGhost(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ghosts = append(ghosts, ghost)
+	}
+
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeDisabled)
+	if *replayFile != "" {
+		replay, err := LoadReplay(*replayFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := ebiten.RunGame(NewReplayViewer(replay, ghosts)); err != nil && err != ebiten.Termination {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	Env = NewEnvironment()
+	agentConfig, err := autopilotConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if World, err = level(*levelSeed); err != nil {
+		log.Fatal(err)
+	}
 
 	// Initialize game state
-	initialLander := &Lander{X: 390, Y: 0}
-	initialDistance := Env.Distance(initialLander)
-
 	game := &Game{
-		Lander:       initialLander,
-		TickLimit:    1000,
-		Score:        0,
-		prevDistance: initialDistance,
-		prevSpeed:    0,
-		hasLanded:    false,
+		TickLimit:   1000,
+		Score:       0,
+		autopilot:   *autopilot,
+		agentConfig: agentConfig,
+		process:     processConfig(),
+		start:       startDistribution(),
+		level:       *levelSeed,
+		recordDir:   *record,
+		config:      flagConfig(flag.CommandLine),
+		ghosts:      ghosts,
 	}
+	game.Lander = game.startLander()
 	if err := ebiten.RunGame(game); err != nil && err != ebiten.Termination {
 		log.Fatal(err)
 	}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Parallelism selects how a search is spread over goroutines.
+type Parallelism int
+
+const (
+	Sequential   Parallelism = iota // A single goroutine
+	RootParallel                    // Independent trees per worker, root child visits summed to pick the action
+	LeafParallel                    // One tree, several rollouts from each expanded leaf
+	TreeParallel                    // One shared tree, workers kept apart by virtual loss
+)
+
+func (p Parallelism) String() string {
+	switch p {
+	case Sequential:
+		return "none"
+	case RootParallel:
+		return "root"
+	case LeafParallel:
+		return "leaf"
+	case TreeParallel:
+		return "tree"
+	}
+	return "unknown"
+}
+
+// ParseParallelism converts a name printed by Parallelism.String back to a Parallelism.
+func ParseParallelism(name string) (Parallelism, error) {
+	for p := Sequential; p <= TreeParallel; p++ {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return Sequential, fmt.Errorf("unknown parallelism %q (want none, root, leaf or tree)", name)
+}
+
+// workers returns the number of goroutines a parallel search uses.
+func (a *Agent) workers() int {
+	if a.Config.Workers > 0 {
+		return a.Config.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// workerRands returns a random number generator for each of n workers.
+// They are seeded from the agent's own generator on first use and kept between searches,
+// so every worker's rollouts repeat with the agent's seed.
+func (a *Agent) workerRands(n int) []*rand.Rand {
+	for len(a.workerRNGs) < n {
+		a.workerRNGs = append(a.workerRNGs, rand.New(rand.NewSource(a.rng.Int63())))
+	}
+	return a.workerRNGs[:n]
+}
+
+// searchRootParallel lets each worker search its own tree from the root state and returns
+// the visits of every root action summed over all the trees, and the nodes of the other
+// workers' trees. The agent's tree keeps only its own statistics, so they stay consistent
+// with its subtrees when it is advanced.
+// Iterations and nodes are split evenly between the workers, each seeded from the agent.
+func (a *Agent) searchRootParallel(budget searchBudget) (int, []int, int) {
+	workers := a.workers()
+	trees := make([]*Agent, workers)
+	trees[0] = a
+	for w := 1; w < workers; w++ {
+		config := a.Config
+		config.Seed = a.rng.Int63()
+		trees[w] = NewAgentWithConfig(a.Tree.Root.state, config)
+	}
+
+	done := make([]int, workers)
+	var wg sync.WaitGroup
+	for w, agent := range trees {
+		share := budget
+		if budget.iterations != math.MaxInt {
+			share.iterations = budget.iterations / workers
+			if w < budget.iterations%workers {
+				share.iterations++
+			}
+		}
+		if budget.maxNodes > 0 {
+			share.maxNodes = max(budget.maxNodes/workers, 1)
+		}
+		wg.Add(1)
+		go func(w int, agent *Agent, share searchBudget) {
+			defer wg.Done()
+			done[w] = agent.search(share)
+		}(w, agent, share)
+	}
+	wg.Wait()
+
+	visits := make([]int, 4)
+	for _, agent := range trees {
+		for _, child := range agent.Tree.Root.children {
+			visits[child.action] += child.visitCount
+		}
+	}
+	nodes := 0
+	for _, agent := range trees[1:] {
+		nodes += agent.Tree.nodes
+	}
+
+	total := 0
+	for _, d := range done {
+		total += d
+	}
+	return total, visits, nodes
+}
+
+// searchLeafParallel descends the tree once per round and runs one rollout per worker from the leaf.
+// The last round only runs the rollouts left in the iteration budget.
+func (a *Agent) searchLeafParallel(budget searchBudget) int {
+	workers := a.workers()
+	rngs := a.workerRands(workers)
+	results := make([]float64, workers)
+	done := 0
+	for budget.allows(done, a.Tree.nodes) {
+		rewards := results[:min(workers, budget.iterations-done)]
+		leaf := a.treePolicy(a.Tree.Root)
+
+		var wg sync.WaitGroup
+		for w := range rewards {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				rewards[w] = a.simulate(leaf, rngs[w])
+			}(w)
+		}
+		wg.Wait()
+
+		for _, reward := range rewards {
+			a.backpropagate(leaf.node, reward)
+		}
+		done += len(rewards)
+	}
+	return done
+}
+
+// searchTreeParallel runs workers on the shared tree.
+// Selection, expansion and backpropagation happen under the agent's lock, rollouts run concurrently.
+// A node on a path being explored carries a virtual loss, steering other workers elsewhere.
+func (a *Agent) searchTreeParallel(budget searchBudget) int {
+	done := 0
+	var wg sync.WaitGroup
+	for _, rng := range a.workerRands(a.workers()) {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+			for {
+				a.mu.Lock()
+				if !budget.allows(done, a.Tree.nodes) {
+					a.mu.Unlock()
+					return
+				}
+				done++
+				leaf := a.treePolicy(a.Tree.Root)
+				a.addVirtualLoss(leaf.node)
+				a.mu.Unlock()
+
+				reward := a.simulate(leaf, rng)
+
+				a.mu.Lock()
+				a.removeVirtualLoss(leaf.node)
+				a.backpropagate(leaf.node, reward)
+				a.mu.Unlock()
+			}
+		}(rng)
+	}
+	wg.Wait()
+	return done
+}
+
+// addVirtualLoss makes the path to node look visited and unrewarding until its rollout returns.
+func (a *Agent) addVirtualLoss(node *Node) {
+	for ; node != nil; node = node.parent {
+		node.visitCount++
+		node.totalReward -= a.Config.VirtualLoss
+	}
+}
+
+// removeVirtualLoss undoes addVirtualLoss.
+func (a *Agent) removeVirtualLoss(node *Node) {
+	for ; node != nil; node = node.parent {
+		node.visitCount--
+		node.totalReward += a.Config.VirtualLoss
+	}
+}
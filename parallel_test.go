@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParallelSearch(t *testing.T) {
+	for _, parallelism := range []Parallelism{Sequential, RootParallel, LeafParallel, TreeParallel} {
+		config := DefaultAgentConfig()
+		config.Parallelism = parallelism
+		config.Workers = 4
+
+		agent := NewAgentWithConfig(&GameState{LanderX: StartX, LanderY: 100, Terrain: NewEnvironment()}, config)
+		action := agent.SelectAction()
+
+		// Open loop, the workers re-simulate the shared nodes' states
+		gusty := NewEnvironment()
+		gusty.Wind = Wind{Turbulence: 0.01, Seed: 1}
+		config.OpenLoop = true
+		if action := NewAgentWithConfig(&GameState{LanderX: StartX, LanderY: 100, Terrain: gusty}, config).SelectAction(); action < 0 || action > 3 {
+			t.Errorf("%s: invalid open-loop action selected: %d", parallelism, action)
+		}
+		if action < 0 || action > 3 {
+			t.Errorf("%s: invalid action selected: %d", parallelism, action)
+		}
+
+		// Root parallel, the agent's own tree holds its share of the simulations
+		root := agent.Tree.Root
+		visits := searchIterations
+		if parallelism == RootParallel {
+			visits = searchIterations / config.Workers
+		}
+		if agent.LastSearch.Simulations != searchIterations || root.visitCount != visits {
+			t.Errorf("%s: expected %d simulations and %d root visits, but got %d and %d",
+				parallelism, searchIterations, visits, agent.LastSearch.Simulations, root.visitCount)
+		}
+
+		// Root parallel, the workers' own trees count towards the nodes searched
+		stats := agent.LastSearch
+		if stats.Nodes != root.size() || (parallelism == RootParallel) != (stats.SearchedNodes > 3*stats.Nodes) ||
+			stats.SearchedNodes < stats.Nodes {
+			t.Errorf("%s: expected the agent's tree of %d nodes and the nodes of every tree searched, but got %+v",
+				parallelism, root.size(), stats)
+		}
+
+		// Every simulation passes through exactly one child of the root
+		childVisits := 0
+		for _, child := range root.children {
+			childVisits += child.visitCount
+		}
+		if childVisits != root.visitCount {
+			t.Errorf("%s: expected children to share %d visits, but got %d", parallelism, root.visitCount, childVisits)
+		}
+	}
+}
+
+func TestRootParallelAdvance(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.Parallelism = RootParallel
+	config.Workers = 4
+	config.Iterations = 400
+	start := &GameState{LanderX: StartX, LanderY: 100, Terrain: NewEnvironment()}
+	agent := NewAgentWithConfig(start, config)
+
+	// The kept subtree's counts match its children, as in a sequential search
+	action := agent.SelectAction()
+	stats := agent.Advance(action, start.Step(action))
+	root := agent.Tree.Root
+	childVisits := 0
+	for _, child := range root.children {
+		childVisits += child.visitCount
+	}
+	if !stats.Reused || stats.RetainedVisits != root.visitCount || childVisits != root.visitCount {
+		t.Errorf("Expected the new root's %d visits to be its children's %d, but got %+v",
+			root.visitCount, childVisits, stats)
+	}
+
+	// Searching again adds the agent's share to the retained visits
+	agent.SelectAction()
+	if agent.Tree.Root.visitCount != stats.RetainedVisits+config.Iterations/config.Workers {
+		t.Errorf("Expected %d visits after the next search, but got %d",
+			stats.RetainedVisits+config.Iterations/config.Workers, agent.Tree.Root.visitCount)
+	}
+}
+
+func TestLeafParallelKeepsIterations(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.Parallelism = LeafParallel
+	config.Workers = 4
+	config.Iterations = 10
+
+	agent := NewAgentWithConfig(&GameState{LanderX: StartX, LanderY: 100, Terrain: NewEnvironment()}, config)
+	agent.SelectAction()
+	if agent.LastSearch.Simulations != 10 || agent.Tree.Root.visitCount != 10 {
+		t.Errorf("Expected the last round to stop at 10 simulations, but got %d and %d root visits",
+			agent.LastSearch.Simulations, agent.Tree.Root.visitCount)
+	}
+}
+
+func TestVirtualLossIsUndone(t *testing.T) {
+	agent := NewAgent(&GameState{LanderY: 100})
+	agent.SelectAction()
+	leaf := agent.treePolicy(agent.Tree.Root).node
+	visits, reward := agent.Tree.Root.visitCount, agent.Tree.Root.totalReward
+
+	agent.addVirtualLoss(leaf)
+	if agent.Tree.Root.visitCount != visits+1 || agent.Tree.Root.totalReward >= reward {
+		t.Errorf("Expected virtual loss to add a losing visit to the root")
+	}
+	agent.removeVirtualLoss(leaf)
+	if agent.Tree.Root.visitCount != visits || math.Abs(agent.Tree.Root.totalReward-reward) > 1e-6 {
+		t.Errorf("Expected root statistics to be restored, but got %d visits and %v reward", agent.Tree.Root.visitCount, agent.Tree.Root.totalReward)
+	}
+}
+
+func TestParseParallelism(t *testing.T) {
+	for _, name := range []string{"none", "root", "leaf", "tree"} {
+		p, err := ParseParallelism(name)
+		if err != nil || p.String() != name {
+			t.Errorf("Expected %q to round trip, but got %v (%v)", name, p, err)
+		}
+	}
+	if _, err := ParseParallelism("gpu"); err == nil {
+		t.Errorf("Expected an error for an unknown parallelism")
+	}
+}
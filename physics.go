@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// TerminationReason describes why an episode ended.
+type TerminationReason int
+
+const (
+	NotTerminated  TerminationReason = iota // Still flying
+	Landed                                  // Touched down safely on the landing pad
+	Crashed                                 // Touched the ground too fast, tilted or off the pad
+	CrashedTerrain                          // Hit the ground with the body, ran into a cliff face or put a leg on steep ground
+	OutOfBounds                             // Left the playable area
+)
+
+func (r TerminationReason) String() string {
+	switch r {
+	case NotTerminated:
+		return "In Air"
+	case Landed:
+		return "Landed"
+	case Crashed:
+		return "Crashed"
+	case CrashedTerrain:
+		return "Crashed into Terrain"
+	case OutOfBounds:
+		return "Out of Bounds"
+	}
+	return "Unknown"
+}
+
+// MarshalText encodes the reason by name, so JSON output stays readable.
+func (r TerminationReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a reason written by MarshalText.
+func (r *TerminationReason) UnmarshalText(text []byte) error {
+	for reason := NotTerminated; reason.String() != "Unknown"; reason++ {
+		if reason.String() == string(text) {
+			*r = reason
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown termination reason %q", text)
+}
+
+// OutcomeScore is the points awarded when an episode ends for the given reason.
+func OutcomeScore(r TerminationReason) float64 {
+	switch r {
+	case Landed:
+		return 100
+	case Crashed, CrashedTerrain, OutOfBounds:
+		return -100
+	}
+	return 0
+}
+
+// Controls are the engines firing during a single tick.
+// The keyboard may fire several engines at once, a discrete action fires at most one.
+type Controls struct {
+	Main  bool
+	Left  bool
+	Right bool
+}
+
+// ActionControls converts a discrete action (0-3, see README) into engine controls.
+func ActionControls(action int) Controls {
+	switch action {
+	case 1: // Fire left orientation engine
+		return Controls{Left: true}
+	case 2: // Fire main engine
+		return Controls{Main: true}
+	case 3: // Fire right orientation engine
+		return Controls{Right: true}
+	}
+	return Controls{} // Do nothing
+}
+
+// StepPhysics advances a state by one tick with the given controls.
+// It is the only simulation in the project: the rendered game and the planner both use it.
+// Without a Terrain the lander flies over flat ground at GroundLevel with the classic pad.
+func StepPhysics(s *GameState, c Controls) (*GameState, TerminationReason) {
+	return stepPhysics(s, c, s.ground().Wind.Force())
+}
+
+// stepPhysics is StepPhysics with the given wind force blowing instead of the level's current wind.
+func stepPhysics(s *GameState, c Controls, wind float64) (*GameState, TerminationReason) {
+	next := s.Copy()
+	if next.IsDone() {
+		return next, next.Reason
+	}
+
+	// Engines cut off when the tank runs dry
+	c = next.FuelCutoff(c)
+	if c.Main {
+		next.FuelUsed += MainFuelBurn
+	}
+	if c.Left {
+		next.FuelUsed += SideFuelBurn
+	}
+	if c.Right {
+		next.FuelUsed += SideFuelBurn
+	}
+	next.FuelUsed = math.Min(next.FuelUsed, FuelCapacity)
+
+	if c.Main {
+		next.VelocityX += math.Sin(next.Angle) * MainThrust
+		next.VelocityY -= math.Cos(next.Angle) * MainThrust
+	}
+	// Orientation engines apply torque, the lander keeps spinning once they stop
+	if c.Left {
+		next.AngularVelocity -= SideThrust
+	}
+	if c.Right {
+		next.AngularVelocity += SideThrust
+	}
+	next.AngularVelocity *= 1 - AngularDamping
+	next.Angle += next.AngularVelocity
+
+	// Gravity applies once per tick, thrusting or not
+	next.VelocityY += Gravity
+	// Wind pushes the lander while it flies, legs on the ground hold it in place
+	if !next.LeftLegOnGround && !next.RightLegOnGround {
+		next.VelocityX += wind
+	}
+	next.LanderX += next.VelocityX
+	next.LanderY += next.VelocityY
+
+	reason := next.ground().settle(next)
+	if reason == NotTerminated && IsOutOfBounds(next.LanderX, next.LanderY) {
+		reason = OutOfBounds
+	}
+
+	if reason != NotTerminated {
+		next.IsDoneFlag = true
+		next.Reason = reason
+	}
+	return next, reason
+}
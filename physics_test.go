@@ -0,0 +1,258 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStepPhysicsGravityOnce(t *testing.T) {
+	gs := &GameState{LanderY: 300}
+	newState, reason := StepPhysics(gs, ActionControls(0))
+	if newState.VelocityY != Gravity {
+		t.Errorf("Expected VelocityY to be %v after one tick, but got %v", Gravity, newState.VelocityY)
+	}
+	if reason != NotTerminated {
+		t.Errorf("Expected reason 'In Air', but got '%s'", reason)
+	}
+}
+
+func TestStepPhysicsMatchesStep(t *testing.T) {
+	gs := &GameState{LanderX: 400, LanderY: 200, VelocityX: 0.3, Angle: 0.2}
+	for action := 0; action < 4; action++ {
+		viaStep := gs.Step(action)
+		viaPhysics, _ := StepPhysics(gs, ActionControls(action))
+		if *viaStep != *viaPhysics {
+			t.Errorf("Action %d: Step and StepPhysics disagree: %+v vs %+v", action, viaStep, viaPhysics)
+		}
+	}
+}
+
+// stepUntilDone runs the physics without firing the engines until the episode ends or ticks run out.
+func stepUntilDone(gs *GameState, ticks int) *GameState {
+	for i := 0; i < ticks && !gs.IsDone(); i++ {
+		gs, _ = StepPhysics(gs, Controls{})
+	}
+	return gs
+}
+
+func TestStepPhysicsTouchdown(t *testing.T) {
+	// Test case 1: Safe landing on the pad, the legs absorb the touchdown before the episode ends
+	gs := &GameState{LanderX: 400, LanderY: 484, VelocityY: 1.0}
+	newState, reason := StepPhysics(gs, Controls{})
+	if reason != NotTerminated || !newState.LeftLegOnGround || !newState.RightLegOnGround {
+		t.Errorf("Expected both legs on the ground, still settling, but got '%s' with %+v", reason, newState)
+	}
+	newState = stepUntilDone(newState, 100)
+	if newState.Reason != Landed {
+		t.Errorf("Expected reason 'Landed', but got '%s'", newState.Reason)
+	}
+	if newState.VelocityY != 0 || math.Abs(newState.LanderY-(GroundLevel-LanderBottomOffset)) > 1 {
+		t.Errorf("Expected lander to rest on the ground, but got %+v", newState)
+	}
+
+	// Test case 2: Too fast, the legs break on touchdown
+	gs = &GameState{LanderX: 400, LanderY: 484, VelocityY: 3.0}
+	if _, reason = StepPhysics(gs, Controls{}); reason != Crashed {
+		t.Errorf("Expected reason 'Crashed', but got '%s'", reason)
+	}
+
+	// Test case 3: Off the pad
+	gs = &GameState{LanderX: 100, LanderY: 484, VelocityY: 1.0}
+	if reason = stepUntilDone(gs, 100).Reason; reason != Crashed {
+		t.Errorf("Expected reason 'Crashed' (off pad), but got '%s'", reason)
+	}
+
+	// Test case 4: A finished episode does not move
+	if again, _ := StepPhysics(newState, Controls{Main: true}); *again != *newState {
+		t.Errorf("Expected a terminated state to stay put, but got %+v", again)
+	}
+}
+
+func TestStepPhysicsPeaksAndBounds(t *testing.T) {
+	env := NewEnvironment()
+
+	// Falling onto the left peak (apex at x=100, y=400)
+	gs := &GameState{LanderX: 100, LanderY: 400, VelocityY: 1.0, Terrain: env}
+	if _, reason := StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected reason 'Crashed into Terrain', but got '%s'", reason)
+	}
+
+	// Without terrain the same fall is still in the air
+	gs.Terrain = nil
+	if _, reason := StepPhysics(gs, Controls{}); reason != NotTerminated {
+		t.Errorf("Expected reason 'In Air' without terrain, but got '%s'", reason)
+	}
+
+	// The planner's Step sees the same peaks
+	gs.Terrain = env
+	if newState := gs.Step(0); newState.Reason != CrashedTerrain {
+		t.Errorf("Expected Step to crash into terrain, but got '%s'", newState.Reason)
+	}
+
+	// Touching down with one leg beside the pad
+	gs = &GameState{LanderX: LandingPadLeft + 5, LanderY: 484, VelocityY: 1.0, Terrain: env}
+	if _, reason := StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected a leg beside the pad to hit the terrain, but got '%s'", reason)
+	}
+
+	// Gently onto the middle of the pad
+	gs = &GameState{LanderX: 400, LanderY: 484, VelocityY: 1.0, Terrain: env}
+	if next := stepUntilDone(gs, 100); next.Reason != Landed || next.LegsOnGround() != 2 {
+		t.Errorf("Expected to land resting on the pad, but got '%s' with %d legs down", next.Reason, next.LegsOnGround())
+	}
+
+	// Flying off the right side of the screen
+	gs = &GameState{LanderX: ScreenWidth + BoundsMargin, LanderY: 100, VelocityX: 1.0, Terrain: env}
+	if _, reason := StepPhysics(gs, Controls{}); reason != OutOfBounds {
+		t.Errorf("Expected reason 'Out of Bounds', but got '%s'", reason)
+	}
+}
+
+func TestStepPhysicsContact(t *testing.T) {
+	// A pad, a cliff up to a plateau, a gentle slope down, a steep bank and a spike
+	env := &Environment{
+		Ground: []Point{
+			{0, 500}, {300, 500}, {302, 400}, {400, 400}, {500, 440},
+			{520, 480}, {640, 480}, {650, 460}, {660, 480}, {800, 480},
+		},
+		TargetX: 150, TargetY: 500, TargetWidth: 300,
+	}
+
+	// Test case 1: Tilted onto a single leg, safe for a landing
+	gs := &GameState{LanderX: 150, LanderY: 481.75, VelocityY: 1.0, Angle: 0.2, Terrain: env}
+	next, reason := StepPhysics(gs, Controls{})
+	if reason != NotTerminated || next.LeftLegOnGround || !next.RightLegOnGround {
+		t.Errorf("Expected to touch down on the right leg, but got '%s' with %+v", reason, next)
+	}
+	// The right leg tips the lander level onto both legs
+	if next = stepUntilDone(next, 200); next.Reason != Landed || next.LegsOnGround() != 2 || math.Abs(next.Angle) > 0.01 {
+		t.Errorf("Expected to settle level on both legs, but got %+v", next)
+	}
+
+	// Test case 2: Too tilted to stand, the lander tips over onto its side
+	gs = &GameState{LanderX: 150, LanderY: 479.25, VelocityY: 0.5, Angle: 1.0, Terrain: env}
+	if next = stepUntilDone(gs, 200); next.Reason != CrashedTerrain {
+		t.Errorf("Expected to tip over, but got %+v", next)
+	}
+
+	// Test case 3: Flying sideways into the cliff face
+	gs = &GameState{LanderX: 284, LanderY: 470, VelocityX: 5, Terrain: env}
+	if _, reason = StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected to crash into the cliff, but got '%s'", reason)
+	}
+
+	// Test case 4: Standing level with the gentle slope, safe but off the pad
+	slope := math.Atan(0.4)
+	gs = &GameState{LanderX: 450, LanderY: 420 - LanderBottomOffset/math.Cos(slope) - 0.5, VelocityY: 1.0, Angle: slope, Terrain: env}
+	next = stepUntilDone(gs, 100)
+	if next.Reason != Crashed || next.LegsOnGround() != 2 {
+		t.Errorf("Expected both legs down off the pad, but got '%s' with %d legs down", next.Reason, next.LegsOnGround())
+	}
+	if !next.isSafeTouchdown(slope) || next.IsSafeLanding() {
+		t.Errorf("Expected the tilt to be safe only relative to the slope")
+	}
+
+	// Test case 5: A leg on the steep bank
+	gs = &GameState{LanderX: 525, LanderY: 444.4, VelocityY: 1.0, Terrain: env}
+	if _, reason = StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected a leg on steep ground to crash, but got '%s'", reason)
+	}
+
+	// Test case 6: The spike hits the body between the legs
+	gs = &GameState{LanderX: 650, LanderY: 464, VelocityY: 1.0, Terrain: env}
+	if _, reason = StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected the spike to hit the body, but got '%s'", reason)
+	}
+}
+
+func TestLegPositions(t *testing.T) {
+	// Level lander: legs straight below the body edges
+	gs := &GameState{LanderX: 100, LanderY: 100}
+	leftX, leftY, rightX, rightY := gs.LegPositions()
+	if leftX != 85 || rightX != 115 || leftY != 115 || rightY != 115 {
+		t.Errorf("Expected legs at (85, 115) and (115, 115), but got (%v, %v) and (%v, %v)", leftX, leftY, rightX, rightY)
+	}
+
+	// Tilted clockwise: the right leg drops below the left one
+	gs.Angle = 0.5
+	_, leftY, _, rightY = gs.LegPositions()
+	if leftY >= rightY {
+		t.Errorf("Expected the right leg to be lower when tilted, but got left %v, right %v", leftY, rightY)
+	}
+}
+
+func TestLanderShape(t *testing.T) {
+	// Level lander: the parts of landerImage around the center
+	gs := &GameState{LanderX: 100, LanderY: 100}
+	body, leftLeg, rightLeg := gs.LanderShape()
+	if body[0] != (Point{90, 85}) || body[2] != (Point{110, 105}) {
+		t.Errorf("Expected the body from (90, 85) to (110, 105), but got %v", body)
+	}
+	if leftLeg[0] != (Point{85, 105}) || leftLeg[2] != (Point{90, 115}) {
+		t.Errorf("Expected the left leg from (85, 105) to (90, 115), but got %v", leftLeg)
+	}
+	if rightLeg[0] != (Point{110, 105}) || rightLeg[2] != (Point{115, 115}) {
+		t.Errorf("Expected the right leg from (110, 105) to (115, 115), but got %v", rightLeg)
+	}
+
+	// Upside down the body is lowest, so even a gentle touchdown on the pad crashes
+	gs = &GameState{LanderX: 400, LanderY: GroundLevel - LanderCenterOffsetY - 0.5, VelocityY: 0.5, Angle: math.Pi}
+	if _, reason := StepPhysics(gs, Controls{}); reason != CrashedTerrain {
+		t.Errorf("Expected the body to hit the ground, but got '%s'", reason)
+	}
+}
+
+func TestStepPhysicsAngularVelocity(t *testing.T) {
+	gs := &GameState{LanderY: 100}
+
+	// Firing the right orientation engine spins the lander clockwise
+	gs, _ = StepPhysics(gs, Controls{Right: true})
+	if gs.AngularVelocity <= 0 || gs.Angle <= 0 {
+		t.Fatalf("Expected a clockwise spin, but got %+v", gs)
+	}
+
+	// Releasing the engine keeps the spin going, slowly damped
+	spin := gs.AngularVelocity
+	angle := gs.Angle
+	gs, _ = StepPhysics(gs, Controls{})
+	if gs.Angle <= angle || gs.AngularVelocity >= spin || gs.AngularVelocity != spin*(1-AngularDamping) {
+		t.Errorf("Expected the lander to keep spinning with damping, but got %+v", gs)
+	}
+
+	// The left engine counters the spin
+	gs, _ = StepPhysics(gs, Controls{Left: true})
+	if gs.AngularVelocity >= 0 {
+		t.Errorf("Expected the left engine to reverse the spin, but got %v", gs.AngularVelocity)
+	}
+}
+
+func TestStepPhysicsFuel(t *testing.T) {
+	// Test case 1: Each engine burns its own rate
+	gs := &GameState{LanderY: 100}
+	gs, _ = StepPhysics(gs, Controls{Main: true, Left: true})
+	if math.Abs(gs.FuelUsed-(MainFuelBurn+SideFuelBurn)) > 1e-9 {
+		t.Errorf("Expected %v fuel used, but got %v", MainFuelBurn+SideFuelBurn, gs.FuelUsed)
+	}
+
+	// Test case 2: Doing nothing is free
+	used := gs.FuelUsed
+	gs, _ = StepPhysics(gs, Controls{})
+	if gs.FuelUsed != used {
+		t.Errorf("Expected no fuel used while idle, but got %v", gs.FuelUsed-used)
+	}
+
+	// Test case 3: An empty tank cuts the engines
+	empty := &GameState{LanderY: 100, FuelUsed: FuelCapacity}
+	next, _ := StepPhysics(empty, Controls{Main: true, Right: true})
+	falling, _ := StepPhysics(empty, Controls{})
+	if *next != *falling || next.Fuel() != 0 {
+		t.Errorf("Expected engines to cut off without fuel, but got %+v", next)
+	}
+
+	// Test case 4: The last drop still fires, but never overdraws the tank
+	nearlyEmpty := &GameState{LanderY: 100, FuelUsed: FuelCapacity - 0.1}
+	next, _ = StepPhysics(nearlyEmpty, Controls{Main: true})
+	if next.VelocityY >= Gravity || next.FuelUsed != FuelCapacity {
+		t.Errorf("Expected a final burn emptying the tank, but got %+v", next)
+	}
+}
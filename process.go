@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ProcessConfig describes an external program flying the lander.
+type ProcessConfig struct {
+	Command     []string      // Program and its arguments
+	MoveTimeout time.Duration // Time the program has to answer each tick
+}
+
+// processFlags registers the external program settings on a flag set.
+// The returned function builds the configuration once the flags are parsed.
+func processFlags(flags *flag.FlagSet) func() ProcessConfig {
+	command := flags.String("agent-command", "", "program flying the lander, arguments separated by spaces")
+	moveTimeout := flags.Duration("move-timeout", time.Second, "time the program has to answer each tick, the first one including its start")
+
+	return func() ProcessConfig {
+		return ProcessConfig{Command: strings.Fields(*command), MoveTimeout: *moveTimeout}
+	}
+}
+
+// processMessage is the line sent to the program every tick.
+type processMessage struct {
+	Tick        int         `json:"tick"`
+	Observation Observation `json:"observation"`
+	Fuel        float64     `json:"fuel"`
+}
+
+// ProcessPilot lets an external program fly the lander. Every tick it writes a line of JSON
+// with the tick, the observation and the fuel left to the program's stdin and reads back
+// a line with the action, 0 to 3, from its stdout. The program's stderr is passed through.
+//
+// A move not answered within MoveTimeout is missed and flown with the engines off; the late
+// answer is skipped. A program that exits, stops reading or answers anything but an action
+// has failed, the lander flies on with the engines off and Close reports the error.
+type ProcessPilot struct {
+	MoveTimeout time.Duration
+	MissedMoves int // Moves the program did not answer in time
+
+	cmd     *exec.Cmd
+	stdin   *os.File
+	stdout  *os.File
+	replies chan string // Lines of the program's stdout, closed when it closes stdout
+	pending int         // Ticks sent that the program has not answered yet
+	tick    int
+	err     error
+}
+
+// StartProcessPilot starts the program for an episode, with the episode's seed in the
+// LANDER_SEED environment variable.
+func StartProcessPilot(config ProcessConfig, seed int64) (*ProcessPilot, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("no program to fly the lander (set -agent-command)")
+	}
+	cmd := exec.Command(config.Command[0], config.Command[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("LANDER_SEED=%d", seed))
+	cmd.Stderr = os.Stderr
+
+	// Own pipes rather than cmd's, so writes can time out and reads outlive Wait
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		stdin.Close()
+		return nil, err
+	}
+	cmd.Stdin, cmd.Stdout = stdinReader, stdoutWriter
+	err = cmd.Start()
+	stdinReader.Close()
+	stdoutWriter.Close()
+	if err != nil {
+		stdin.Close()
+		stdout.Close()
+		return nil, err
+	}
+
+	p := &ProcessPilot{
+		MoveTimeout: config.MoveTimeout,
+		cmd:         cmd,
+		stdin:       stdin,
+		stdout:      stdout,
+		replies:     make(chan string, 16),
+	}
+	go func() {
+		defer close(p.replies)
+		lines := bufio.NewScanner(stdout)
+		for lines.Scan() {
+			p.replies <- lines.Text()
+		}
+	}()
+	return p, nil
+}
+
+func (p *ProcessPilot) Action(state *GameState) int {
+	if p.err != nil {
+		return 0
+	}
+	deadline := time.Now().Add(p.MoveTimeout)
+	line, err := json.Marshal(processMessage{Tick: p.tick, Observation: state.Observation(), Fuel: state.Fuel()})
+	if err != nil {
+		p.err = err
+		return 0
+	}
+	p.tick++
+
+	p.stdin.SetWriteDeadline(deadline)
+	if _, err := p.stdin.Write(append(line, '\n')); errors.Is(err, os.ErrDeadlineExceeded) {
+		p.err = fmt.Errorf("tick %d: the program stopped reading its input", p.tick-1)
+		return 0
+	} else if errors.Is(err, syscall.EPIPE) {
+		p.err = fmt.Errorf("tick %d: the program closed its input", p.tick-1)
+		return 0
+	} else if err != nil {
+		p.err = fmt.Errorf("tick %d: %w", p.tick-1, err)
+		return 0
+	}
+	p.pending++
+
+	timeout := time.NewTimer(time.Until(deadline))
+	defer timeout.Stop()
+	for {
+		select {
+		case reply, ok := <-p.replies:
+			if !ok {
+				p.err = fmt.Errorf("tick %d: the program closed its output", p.tick-1)
+				return 0
+			}
+			p.pending--
+			if p.pending > 0 {
+				continue // The late answer to a missed move
+			}
+			action, err := strconv.Atoi(strings.TrimSpace(reply))
+			if err != nil || action < 0 || action > 3 {
+				p.err = fmt.Errorf("tick %d: invalid action %q (want 0 to 3)", p.tick-1, reply)
+				return 0
+			}
+			return action
+		case <-timeout.C:
+			p.MissedMoves++
+			return 0
+		}
+	}
+}
+
+// Close ends the program by closing its input, killing it if it does not exit within a second,
+// and returns the error that made it fail, if any.
+func (p *ProcessPilot) Close() error {
+	p.stdin.Close()
+	go func() {
+		for range p.replies {
+			// Nobody reads the answers any more, drop them so the reader gets to the end
+		}
+	}()
+	defer p.stdout.Close() // Ends the reader even if a child of the program holds the output open
+
+	exited := make(chan error, 1)
+	go func() { exited <- p.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		if p.err == nil && err != nil {
+			p.err = fmt.Errorf("the program failed: %w", err)
+		}
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		<-exited
+	}
+	return p.err
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperBot is the external program of the process tests: the test binary runs itself
+// with LANDER_TEST_BOT set to how the bot should behave.
+func TestHelperBot(t *testing.T) {
+	mode := os.Getenv("LANDER_TEST_BOT")
+	if mode == "" {
+		return
+	}
+	if mode == "exit" {
+		os.Exit(3)
+	}
+	lines := bufio.NewScanner(os.Stdin)
+	for lines.Scan() {
+		var msg processMessage
+		if err := json.Unmarshal(lines.Bytes(), &msg); err != nil {
+			os.Exit(2)
+		}
+		switch mode {
+		case "ticks":
+			fmt.Println(msg.Tick % 4)
+		case "slow":
+			if msg.Tick == 1 {
+				time.Sleep(300 * time.Millisecond)
+			}
+			fmt.Println(msg.Tick % 4)
+		case "seed":
+			fmt.Println(os.Getenv("LANDER_SEED"))
+		case "garbage":
+			fmt.Println("fly")
+		case "chatty":
+			for i := 0; i < 100; i++ {
+				fmt.Println(msg.Tick % 4)
+			}
+		}
+	}
+	os.Exit(0)
+}
+
+// startHelperBot starts the test binary as a bot behaving as mode.
+func startHelperBot(t *testing.T, mode string, seed int64) *ProcessPilot {
+	t.Setenv("LANDER_TEST_BOT", mode)
+	p, err := StartProcessPilot(ProcessConfig{
+		Command:     []string{os.Args[0], "-test.run=^TestHelperBot$"},
+		MoveTimeout: 5 * time.Second,
+	}, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProcessPilot(t *testing.T) {
+	state := StartDistribution{}.Sample(0, NewEnvironment())
+
+	// Test case 1: Every tick is answered in turn, and the program exits when the episode ends
+	p := startHelperBot(t, "ticks", 1)
+	for tick := 0; tick < 6; tick++ {
+		if action := p.Action(state); action != tick%4 {
+			t.Errorf("Expected action %d on tick %d, but got %d", tick%4, tick, action)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Expected the program to exit cleanly, but got %v", err)
+	}
+
+	// Test case 2: The program is told its episode's seed
+	p = startHelperBot(t, "seed", 3)
+	if action := p.Action(state); action != 3 {
+		t.Errorf("Expected the seed as the action, but got %d", action)
+	}
+	p.Close()
+
+	// Test case 3: A slow move is missed and its late answer skipped
+	p = startHelperBot(t, "slow", 1)
+	p.Action(state)
+	p.MoveTimeout = 150 * time.Millisecond
+	if action := p.Action(state); action != 0 || p.MissedMoves != 1 {
+		t.Errorf("Expected a missed move flown with the engines off, but got action %d and %d missed", action, p.MissedMoves)
+	}
+	p.MoveTimeout = 5 * time.Second
+	if action := p.Action(state); action != 2 {
+		t.Errorf("Expected the answer to tick 2 after the late one, but got %d", action)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Expected missed moves not to fail the program, but got %v", err)
+	}
+
+	// Test case 4: Extra lines are dropped once the episode is over, so the reader finishes
+	p = startHelperBot(t, "chatty", 1)
+	p.Action(state)
+	if err := p.Close(); err != nil {
+		t.Errorf("Expected the program to exit cleanly, but got %v", err)
+	}
+	for wait := 0; len(p.replies) > 0 && wait < 500; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, open := <-p.replies; open {
+		t.Errorf("Expected the output reader to finish after Close")
+	}
+}
+
+func TestProcessPilotFails(t *testing.T) {
+	state := StartDistribution{}.Sample(0, NewEnvironment())
+
+	// Test case 1: Anything but an action
+	p := startHelperBot(t, "garbage", 1)
+	if action := p.Action(state); action != 0 {
+		t.Errorf("Expected the engines off after an invalid action, but got %d", action)
+	}
+	if err := p.Close(); err == nil || !strings.Contains(err.Error(), `invalid action "fly"`) {
+		t.Errorf("Expected an invalid action error, but got %v", err)
+	}
+
+	// Test case 2: The program exits before answering
+	p = startHelperBot(t, "exit", 1)
+	result := RunEpisode(p, state, 1000)
+	if result.Reason != Crashed {
+		t.Errorf("Expected the lander to fall with the engines off, but got %+v", result)
+	}
+	if err := p.Close(); err == nil || !strings.Contains(err.Error(), "closed its output") {
+		t.Errorf("Expected a closed output error, but got %v", err)
+	}
+
+	// Test case 3: Nothing to run
+	if _, err := StartProcessPilot(ProcessConfig{}, 1); err == nil {
+		t.Errorf("Expected an error without a command")
+	}
+}
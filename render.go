@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+// RenderPNG draws the state as a PNG image without a window, for headless tools.
+// It shows the ground, the landing pad flags and the lander's collision shape
+// in the game's colors, at the game's screen size.
+func RenderPNG(w io.Writer, s *GameState) error {
+	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+	draw.Draw(img, img.Bounds(), image.Black, image.Point{}, draw.Src)
+
+	env := s.ground()
+	for i := 1; i < len(env.Ground); i++ {
+		drawLine(img, env.Ground[i-1], env.Ground[i], color.White)
+	}
+	padLeft, padRight := env.TargetX-env.TargetWidth/2, env.TargetX+env.TargetWidth/2
+	drawLine(img, Point{padLeft, env.TargetY}, Point{padLeft, env.TargetY - 20}, color.White)
+	drawLine(img, Point{padRight, env.TargetY}, Point{padRight, env.TargetY - 20}, color.White)
+
+	landerColor := color.RGBA{255, 0, 255, 255}
+	body, leftLeg, rightLeg := s.LanderShape()
+	for _, part := range [][]Point{body, leftLeg, rightLeg} {
+		fillConvex(img, part, landerColor)
+	}
+	return png.Encode(w, img)
+}
+
+// drawLine draws a one pixel wide line from a to b.
+func drawLine(img *image.RGBA, a, b Point, c color.Color) {
+	steps := int(math.Ceil(math.Max(math.Abs(b.X-a.X), math.Abs(b.Y-a.Y))))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		img.Set(int(math.Round(a.X+(b.X-a.X)*t)), int(math.Round(a.Y+(b.Y-a.Y)*t)), c)
+	}
+}
+
+// fillConvex fills the pixels whose centers lie inside the convex polygon.
+func fillConvex(img *image.RGBA, polygon []Point, c color.Color) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range polygon {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+
+	bounds := img.Bounds()
+	for y := max(int(minY), bounds.Min.Y); y <= min(int(maxY), bounds.Max.Y-1); y++ {
+		for x := max(int(minX), bounds.Min.X); x <= min(int(maxX), bounds.Max.X-1); x++ {
+			if insideConvex(polygon, Point{float64(x) + 0.5, float64(y) + 0.5}) {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// insideConvex checks if p lies inside the convex polygon, whichever way it winds.
+func insideConvex(polygon []Point, p Point) bool {
+	sign := 0.0
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		cross := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+		if cross == 0 {
+			continue
+		}
+		if sign == 0 {
+			sign = cross
+		} else if (cross > 0) != (sign > 0) {
+			return false
+		}
+	}
+	return true
+}
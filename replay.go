@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// replayFormatVersion is bumped whenever the layout of a replay file changes.
+const replayFormatVersion = 1
+
+// Replay is the record of a single episode: how it was set up and what happened on every tick.
+// It is stored as JSON lines, the header first and then one line per tick, so replays can be
+// searched and cut with line-based tools.
+type Replay struct {
+	Header ReplayHeader
+	Ticks  []ReplayTick
+}
+
+// ReplayHeader is the first line of a replay file.
+type ReplayHeader struct {
+	Version int               `json:"version"`
+	Seed    int64             `json:"seed"`   // Seed of the episode's start, wind and pilot
+	Pilot   string            `json:"pilot"`  // Who flew the episode, an agent name or "game"
+	Config  map[string]string `json:"config"` // Every flag the episode was played with
+	Level   *Environment      `json:"level"`  // Ground, landing pad and wind settings
+	Start   GameState         `json:"start"`
+}
+
+// ReplayTick is a line of a replay file after the header.
+type ReplayTick struct {
+	Tick     int       `json:"tick"`     // Ticks since the start, counting from 1
+	Action   int       `json:"action"`   // Discrete action taken, -1 when flown from the keyboard
+	Controls Controls  `json:"controls"` // Engines that fired, after the fuel cutoff
+	Wind     float64   `json:"wind"`     // Wind force blowing during the tick
+	Reward   float64   `json:"reward"`
+	State    GameState `json:"state"` // State at the end of the tick
+}
+
+// NewReplay starts recording an episode from start.
+// Config is usually flagConfig of the flags the episode was set up with.
+func NewReplay(seed int64, pilot string, config map[string]string, start *GameState) *Replay {
+	replay := &Replay{Header: ReplayHeader{
+		Version: replayFormatVersion,
+		Seed:    seed,
+		Pilot:   pilot,
+		Config:  config,
+		Level:   start.ground(),
+		Start:   *start,
+	}}
+	replay.Header.Start.Terrain = nil
+	return replay
+}
+
+// Record appends a tick that took action, fired the engines in c with wind blowing,
+// scored reward and ended in next.
+func (r *Replay) Record(action int, c Controls, wind, reward float64, next *GameState) {
+	state := *next
+	state.Terrain = nil // The level is in the header
+	r.Ticks = append(r.Ticks, ReplayTick{
+		Tick:     len(r.Ticks) + 1,
+		Action:   action,
+		Controls: c,
+		Wind:     wind,
+		Reward:   reward,
+		State:    state,
+	})
+}
+
+// State returns the state after tick ticks, the start state for tick 0.
+func (r *Replay) State(tick int) *GameState {
+	state := r.Header.Start
+	if tick > 0 {
+		state = r.Ticks[tick-1].State
+	}
+	state.Terrain = r.Header.Level
+	return &state
+}
+
+// Score returns the reward collected up to and including tick.
+func (r *Replay) Score(tick int) float64 {
+	score := 0.0
+	for _, t := range r.Ticks[:tick] {
+		score += t.Reward
+	}
+	return score
+}
+
+// flagConfig returns the value of every flag in flags by name.
+func flagConfig(flags *flag.FlagSet) map[string]string {
+	config := map[string]string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		config[f.Name] = f.Value.String()
+	})
+	return config
+}
+
+// WriteReplay writes the replay as JSON lines.
+func WriteReplay(w io.Writer, r *Replay) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(r.Header); err != nil {
+		return err
+	}
+	for _, tick := range r.Ticks {
+		if err := encoder.Encode(tick); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadReplay decodes a replay written by WriteReplay.
+func ReadReplay(r io.Reader) (*Replay, error) {
+	decoder := json.NewDecoder(r)
+	replay := &Replay{}
+	if err := decoder.Decode(&replay.Header); err != nil {
+		return nil, err
+	}
+	if replay.Header.Version != replayFormatVersion {
+		return nil, fmt.Errorf("unsupported replay format version %d", replay.Header.Version)
+	}
+	if replay.Header.Level == nil || len(replay.Header.Level.Ground) < 2 {
+		return nil, errors.New("replay has no level")
+	}
+
+	for {
+		var tick ReplayTick
+		err := decoder.Decode(&tick)
+		if err == io.EOF {
+			return replay, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if tick.Tick != len(replay.Ticks)+1 {
+			return nil, fmt.Errorf("expected tick %d, but got %d", len(replay.Ticks)+1, tick.Tick)
+		}
+		replay.Ticks = append(replay.Ticks, tick)
+	}
+}
+
+// SaveReplay writes the replay to a date_time_pilot_seed.jsonl file in dir and returns its name.
+// Existing files are never overwritten: a replay saved under a name already taken, say by another
+// process recording the same seed in the same second, gets a _2, _3, ... suffix.
+func SaveReplay(dir string, r *Replay) (string, error) {
+	name := fmt.Sprintf("%s_%s_%d", time.Now().Format("20060102_150405"), r.Header.Pilot, r.Header.Seed)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	filename := filepath.Join(dir, name+".jsonl")
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	for n := 2; errors.Is(err, os.ErrExist); n++ {
+		filename = filepath.Join(dir, fmt.Sprintf("%s_%d.jsonl", name, n))
+		file, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	if err := WriteReplay(buffered, r); err != nil {
+		return "", err
+	}
+	if err := buffered.Flush(); err != nil {
+		return "", err
+	}
+	return filename, file.Close()
+}
+
+// LoadReplay reads a replay file written by SaveReplay.
+func LoadReplay(filename string) (*Replay, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	replay, err := ReadReplay(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return replay, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplayRoundTrip(t *testing.T) {
+	env, err := GenerateTerrain(DefaultTerrainConfig(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Wind = Wind{Bias: 0.002, Turbulence: 0.005, Seed: 5}
+	start := DefaultStartDistribution().Sample(5, env)
+	replay := NewReplay(5, "heuristic", map[string]string{"terrain": "random"}, start)
+	result := RecordEpisode(PolicyPilot{Policy: HeuristicPolicy{}}, start, 2000, replay)
+	if len(replay.Ticks) != result.Ticks || replay.Score(len(replay.Ticks)) != result.Score {
+		t.Fatalf("Expected %d ticks scoring %v, but recorded %d scoring %v",
+			result.Ticks, result.Score, len(replay.Ticks), replay.Score(len(replay.Ticks)))
+	}
+
+	filename, err := SaveReplay(t.TempDir(), replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadReplay(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Header.Seed != 5 || loaded.Header.Pilot != "heuristic" || loaded.Header.Config["terrain"] != "random" {
+		t.Errorf("Expected the header to survive, but got %+v", loaded.Header)
+	}
+	if len(loaded.Header.Level.Ground) != len(env.Ground) || loaded.Header.Level.Wind.Turbulence != 0.005 {
+		t.Errorf("Expected the level to survive, but got %+v", loaded.Header.Level)
+	}
+
+	// Every recorded tick follows from the one before, bit for bit
+	if len(loaded.Ticks) != len(replay.Ticks) {
+		t.Fatalf("Expected %d ticks, but got %d", len(replay.Ticks), len(loaded.Ticks))
+	}
+	for i, tick := range loaded.Ticks {
+		if tick != replay.Ticks[i] {
+			t.Fatalf("Tick %d differs: %+v vs %+v", tick.Tick, tick, replay.Ticks[i])
+		}
+		next, _ := stepPhysics(loaded.State(i), tick.Controls, tick.Wind)
+		next.Terrain = nil
+		if *next != tick.State {
+			t.Fatalf("Tick %d does not follow from the one before: %+v vs %+v", tick.Tick, *next, tick.State)
+		}
+	}
+	if last := loaded.State(len(loaded.Ticks)); last.Reason != result.Reason || last.Terrain != loaded.Header.Level {
+		t.Errorf("Expected the last state to end the episode with %v on the recorded level, but got %+v", result.Reason, last)
+	}
+}
+
+func TestReadReplayRejectsBadFiles(t *testing.T) {
+	var buf bytes.Buffer
+	replay := NewReplay(1, "idle", nil, &GameState{})
+	replay.Record(0, Controls{}, 0, 0, &GameState{})
+	replay.Record(0, Controls{}, 0, 0, &GameState{})
+	if err := WriteReplay(&buf, replay); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	// Test case 1: Another version
+	if _, err := ReadReplay(strings.NewReader(strings.Replace(lines[0], `"version":1`, `"version":99`, 1))); err == nil {
+		t.Errorf("Expected an error for an unknown version")
+	}
+
+	// Test case 2: A tick is missing
+	if _, err := ReadReplay(strings.NewReader(lines[0] + lines[2])); err == nil {
+		t.Errorf("Expected an error for a missing tick")
+	}
+
+	// Test case 3: Intact
+	if loaded, err := ReadReplay(&buf); err != nil || len(loaded.Ticks) != 2 {
+		t.Errorf("Expected 2 ticks, but got %v", err)
+	}
+}
+
+func TestSaveReplayKeepsEveryFile(t *testing.T) {
+	dir := t.TempDir()
+	replay := NewReplay(1, "idle", nil, &GameState{})
+	replay.Record(0, Controls{}, 0, 0, &GameState{})
+
+	// The same pilot and seed saved twice, most likely within the same second
+	first, err := SaveReplay(dir, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := SaveReplay(dir, replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("Expected two files, but both were saved to %s", first)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 2 {
+		t.Errorf("Expected 2 files, but found %d", len(files))
+	}
+	for _, filename := range []string{first, second} {
+		if loaded, err := LoadReplay(filename); err != nil || len(loaded.Ticks) != 1 {
+			t.Errorf("Expected %s to hold the replay, but got %v", filename, err)
+		}
+	}
+}
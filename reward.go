@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Reward scores a single tick, from prev to next with the given engines firing.
+// The reward of an episode or rollout is the sum over its ticks.
+type Reward interface {
+	Reward(prev, next *GameState, c Controls) float64
+}
+
+// ScoreReward is how the game and the headless runner score episodes, as described in the README.
+var ScoreReward Reward = GymReward{}
+
+// Scale of the shaping terms: one unit of distance is half the screen width,
+// one unit of speed is a little more than a free fall from the top of the screen reaches.
+const (
+	distanceUnit = ScreenWidth / 2.0
+	speedUnit    = 10.0
+)
+
+// GymReward is the README's shaping, following Gym's Lunar Lander: the change in
+// closeness to the pad, slowness, levelness and leg contact, minus engine usage.
+// As in Gym, the tick that ends the episode scores only 100 for landing or -100 for crashing.
+type GymReward struct{}
+
+func (GymReward) Reward(prev, next *GameState, c Controls) float64 {
+	if prev.IsDone() {
+		return 0
+	}
+	if next.IsDone() {
+		return OutcomeScore(next.Reason)
+	}
+	reward := gymShaping(next) - gymShaping(prev)
+
+	// Engine usage, engines without fuel do not fire
+	c = prev.FuelCutoff(c)
+	if c.Main {
+		reward -= 0.3
+	}
+	if c.Left {
+		reward -= 0.03
+	}
+	if c.Right {
+		reward -= 0.03
+	}
+	return reward
+}
+
+func gymShaping(s *GameState) float64 {
+	return shapingPotential(s) + 10*float64(s.LegsOnGround())
+}
+
+// SparseReward only scores the outcome of an episode.
+type SparseReward struct{}
+
+func (SparseReward) Reward(prev, next *GameState, c Controls) float64 {
+	if prev.IsDone() {
+		return 0
+	}
+	return OutcomeScore(next.Reason)
+}
+
+// PotentialReward is potential-based shaping, Discount*potential(next) - potential(prev),
+// plus the outcome score. Terminal states have zero potential, so the shaping does not
+// change which policy is optimal.
+type PotentialReward struct {
+	Discount float64
+}
+
+func (p PotentialReward) Reward(prev, next *GameState, c Controls) float64 {
+	if prev.IsDone() {
+		return 0
+	}
+	nextPotential := 0.0
+	if !next.IsDone() {
+		nextPotential = shapingPotential(next)
+	}
+	return p.Discount*nextPotential - shapingPotential(prev) + OutcomeScore(next.Reason)
+}
+
+// shapingPotential is higher the closer, slower and more level the lander is.
+func shapingPotential(s *GameState) float64 {
+	targetX, targetY := padCenter(s)
+	distance := math.Hypot(s.LanderX-targetX, s.LanderY-targetY) / distanceUnit
+	speed := math.Hypot(s.VelocityX, s.VelocityY) / speedUnit
+	return -100*distance - 100*speed - 100*math.Abs(s.Angle)
+}
+
+// padCenter returns where the lander's center rests after landing in the middle of the pad.
+func padCenter(s *GameState) (float64, float64) {
+	env := s.ground()
+	return env.TargetX, env.TargetY - LanderBottomOffset
+}
+
+// ParseReward returns a built-in reward by name.
+func ParseReward(name string) (Reward, error) {
+	switch name {
+	case "gym":
+		return GymReward{}, nil
+	case "sparse":
+		return SparseReward{}, nil
+	case "potential":
+		return PotentialReward{Discount: 0.99}, nil
+	}
+	return nil, fmt.Errorf("unknown reward %q (want gym, sparse or potential)", name)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRewards(t *testing.T) {
+	high := &GameState{LanderX: 400, LanderY: 85}                   // 400 pixels above the pad, one unit away
+	falling := &GameState{LanderX: 400, LanderY: 285, VelocityY: 5} // Half a unit away, half a unit of speed
+	// On the pad center, standing on both legs
+	resting := &GameState{LanderX: 400, LanderY: 485, LeftLegOnGround: true, RightLegOnGround: true}
+	landed := &GameState{LanderX: 400, LanderY: 485, IsDoneFlag: true, Reason: Landed}
+	crashed := &GameState{LanderX: 100, LanderY: 485, IsDoneFlag: true, Reason: Crashed}
+
+	tests := []struct {
+		name       string
+		reward     Reward
+		prev, next *GameState
+		controls   Controls
+		want       float64
+	}{
+		{"gym shaping", GymReward{}, high, resting, Controls{}, 120},
+		{"gym main engine", GymReward{}, high, resting, Controls{Main: true}, 119.7},
+		{"gym side engines", GymReward{}, high, resting, Controls{Left: true, Right: true}, 119.94},
+		{"gym moving away", GymReward{}, falling, high, Controls{}, 0},
+		{"gym landing", GymReward{}, falling, landed, Controls{Main: true}, 100},
+		{"gym crash", GymReward{}, falling, crashed, Controls{}, -100},
+		{"gym after the end", GymReward{}, landed, landed, Controls{}, 0},
+		{"sparse in flight", SparseReward{}, high, falling, Controls{Main: true}, 0},
+		{"sparse landing", SparseReward{}, falling, landed, Controls{}, 100},
+		{"sparse crash", SparseReward{}, falling, crashed, Controls{}, -100},
+		{"potential shaping", PotentialReward{Discount: 0.99}, high, falling, Controls{}, 1},
+		{"potential landing", PotentialReward{Discount: 0.99}, falling, landed, Controls{}, 200},
+		{"potential crash", PotentialReward{Discount: 0.99}, falling, crashed, Controls{}, 0},
+	}
+	for _, tt := range tests {
+		got := tt.reward.Reward(tt.prev, tt.next, tt.controls)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: expected reward %v, but got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRewardUsesTerrainTarget(t *testing.T) {
+	env := NewEnvironment()
+	env.TargetX = 200
+	onTarget := &GameState{LanderX: 200, LanderY: GroundLevel - LanderBottomOffset, Terrain: env}
+	if p := shapingPotential(onTarget); p != 0 {
+		t.Errorf("Expected zero potential on the environment's target, but got %v", p)
+	}
+}
+
+func TestParseReward(t *testing.T) {
+	for _, name := range []string{"gym", "sparse", "potential"} {
+		if _, err := ParseReward(name); err != nil {
+			t.Errorf("Expected %q to be a built-in reward, but got %v", name, err)
+		}
+	}
+	if _, err := ParseReward("dense"); err == nil {
+		t.Errorf("Expected an error for an unknown reward")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// RolloutPolicy picks the actions of MCTS rollouts. PolicyPilot flies one as a Pilot.
+// Policies must be safe for concurrent use by parallel searches, and draw any random
+// choice from rng, which belongs to the calling goroutine, so searches can be repeated.
+type RolloutPolicy interface {
+	Action(state *GameState, rng *rand.Rand) int
+}
+
+// RandomPolicy picks uniformly random actions.
+type RandomPolicy struct{}
+
+func (RandomPolicy) Action(state *GameState, rng *rand.Rand) int {
+	return rng.Intn(4)
+}
+
+// HeuristicPolicy is a hand-written controller: lean toward the pad, level out
+// near the ground and keep the descent slower the lower the lander gets.
+type HeuristicPolicy struct{}
+
+func (HeuristicPolicy) Action(s *GameState, _ *rand.Rand) int {
+	targetX, restY := padCenter(s)
+	height := restY - s.LanderY
+
+	// Lean toward the pad while damping sideways drift, upright for the touchdown
+	targetAngle := 0.0
+	if height > 30 {
+		targetAngle = math.Max(-0.25, math.Min(0.25, (targetX-s.LanderX)*0.003-s.VelocityX*0.3))
+	}
+
+	// Falling well too fast for the height: brake before anything else, unless tilted too far
+	// for the main engine to slow the descent
+	descentLimit := SafeVerticalSpeed/2 + height*0.01
+	if s.VelocityY > descentLimit+0.2 && math.Abs(s.Angle) < 0.3 {
+		return 2
+	}
+
+	// Spin toward the target angle, slowing the spin as it gets close
+	wantSpin := (targetAngle - s.Angle) * 0.1
+	if s.AngularVelocity > wantSpin+SideThrust/2 {
+		return 1 // Left orientation engine turns counter-clockwise
+	}
+	if s.AngularVelocity < wantSpin-SideThrust/2 {
+		return 3 // Right orientation engine turns clockwise
+	}
+
+	// Throttle the descent, the closer to the ground the slower
+	if s.VelocityY > descentLimit {
+		return 2
+	}
+	return 0
+}
+
+// EpsilonPolicy follows Policy but picks a random action with probability Epsilon.
+type EpsilonPolicy struct {
+	Epsilon float64
+	Policy  RolloutPolicy
+}
+
+func (p EpsilonPolicy) Action(state *GameState, rng *rand.Rand) int {
+	if rng.Float64() < p.Epsilon {
+		return rng.Intn(4)
+	}
+	return p.Policy.Action(state, rng)
+}
+
+// ParseRolloutPolicy returns a built-in rollout policy by name.
+// epsilon is the random share of the mixed policy.
+func ParseRolloutPolicy(name string, epsilon float64) (RolloutPolicy, error) {
+	switch name {
+	case "random":
+		return RandomPolicy{}, nil
+	case "heuristic":
+		return HeuristicPolicy{}, nil
+	case "mixed":
+		return EpsilonPolicy{Epsilon: epsilon, Policy: HeuristicPolicy{}}, nil
+	}
+	return nil, fmt.Errorf("unknown rollout policy %q (want random, heuristic or mixed)", name)
+}
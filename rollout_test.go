@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHeuristicPolicyLands(t *testing.T) {
+	result := RunEpisode(PolicyPilot{Policy: HeuristicPolicy{}}, StartDistribution{}.Sample(0, NewEnvironment()), 1000)
+	if result.Reason != Landed {
+		t.Errorf("Expected the heuristic to land, but got %+v", result)
+	}
+}
+
+func TestHeuristicPolicyLevelsOut(t *testing.T) {
+	// Test case 1: Tilted right close to the ground
+	gs := &GameState{LanderX: 400, LanderY: 460, Angle: 0.3}
+	if action := (HeuristicPolicy{}).Action(gs, nil); action != 1 {
+		t.Errorf("Expected the left orientation engine, but got %d", action)
+	}
+
+	// Test case 2: Level but falling fast close to the ground
+	gs = &GameState{LanderX: 400, LanderY: 460, VelocityY: 3}
+	if action := (HeuristicPolicy{}).Action(gs, nil); action != 2 {
+		t.Errorf("Expected the main engine, but got %d", action)
+	}
+}
+
+func TestEpsilonPolicy(t *testing.T) {
+	gs := &GameState{LanderX: 400, LanderY: 460, VelocityY: 3}
+	rng := rand.New(rand.NewSource(1))
+
+	// Test case 1: Never random
+	never := EpsilonPolicy{Epsilon: 0, Policy: HeuristicPolicy{}}
+	for i := 0; i < 100; i++ {
+		if action := never.Action(gs, rng); action != 2 {
+			t.Fatalf("Expected the heuristic's action 2, but got %d", action)
+		}
+	}
+
+	// Test case 2: Always random
+	always := EpsilonPolicy{Epsilon: 1, Policy: HeuristicPolicy{}}
+	seen := map[int]bool{}
+	for i := 0; i < 1000; i++ {
+		seen[always.Action(gs, rng)] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected all 4 actions from a fully random policy, but got %v", seen)
+	}
+
+	// Test case 3: The same seed picks the same actions
+	mixed := EpsilonPolicy{Epsilon: 0.5, Policy: HeuristicPolicy{}}
+	a, b := rand.New(rand.NewSource(7)), rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		if x, y := mixed.Action(gs, a), mixed.Action(gs, b); x != y {
+			t.Fatalf("Expected seeded policies to agree, but got %d and %d at step %d", x, y, i)
+		}
+	}
+}
+
+func TestParseRolloutPolicy(t *testing.T) {
+	for _, name := range []string{"random", "heuristic", "mixed"} {
+		if _, err := ParseRolloutPolicy(name, 0.1); err != nil {
+			t.Errorf("Expected %q to be a built-in rollout policy, but got %v", name, err)
+		}
+	}
+	if _, err := ParseRolloutPolicy("learned", 0.1); err == nil {
+		t.Errorf("Expected an error for an unknown rollout policy")
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Pilot chooses the action for each tick of an episode.
+type Pilot interface {
+	Action(state *GameState) int
+}
+
+// PilotFunc adapts an ordinary function to the Pilot interface.
+type PilotFunc func(state *GameState) int
+
+func (f PilotFunc) Action(state *GameState) int {
+	return f(state)
+}
+
+// MCTSPilot plans every action with MCTS, reusing the subtree of the previous action.
+type MCTSPilot struct {
+	Config     AgentConfig
+	agent      *Agent
+	lastAction int
+	LastReuse  AdvanceStats // How much of the previous search the last decision kept
+	LastSearch SearchStats  // What the last decision's search did
+}
+
+func (p *MCTSPilot) Action(state *GameState) int {
+	if p.agent == nil {
+		p.agent = NewAgentWithConfig(state, p.Config)
+	} else {
+		p.LastReuse = p.agent.Advance(p.lastAction, state)
+	}
+	p.lastAction = p.agent.SelectAction()
+	p.LastSearch = p.agent.LastSearch
+	return p.lastAction
+}
+
+// PolicyPilot flies a rollout policy, drawing its random choices from Rand.
+type PolicyPilot struct {
+	Policy RolloutPolicy
+	Rand   *rand.Rand // May be nil for deterministic policies
+}
+
+func (p PolicyPilot) Action(state *GameState) int {
+	return p.Policy.Action(state, p.Rand)
+}
+
+// NewPilot creates a pilot by name, as used on the command line.
+// The same seed makes the same choices; the agent configuration only applies to search based pilots.
+func NewPilot(name string, seed int64, config AgentConfig) (Pilot, error) {
+	switch name {
+	case "mcts":
+		config.Seed = seed
+		return &MCTSPilot{Config: config}, nil
+	case "random":
+		return PolicyPilot{Policy: RandomPolicy{}, Rand: rand.New(rand.NewSource(seed))}, nil
+	case "heuristic":
+		return PolicyPilot{Policy: HeuristicPolicy{}}, nil
+	case "idle":
+		return PilotFunc(func(*GameState) int { return 0 }), nil
+	}
+	return nil, fmt.Errorf("unknown agent %q (want mcts, random, heuristic or idle)", name)
+}
+
+// EpisodeResult summarizes a single headless episode.
+type EpisodeResult struct {
+	Episode      int               `json:"episode"`
+	Seed         int64             `json:"seed"`  // Seed of the start state, the wind and the pilot, replays the episode
+	Level        int64             `json:"level"` // Seed of the generated terrain
+	Reason       TerminationReason `json:"reason"`
+	Truncated    bool              `json:"truncated"` // Hit the tick limit before terminating
+	Score        float64           `json:"score"`
+	Ticks        int               `json:"ticks"`
+	FuelUsed     float64           `json:"fuel_used"`
+	DecisionTime time.Duration     `json:"decision_time_ns"` // Total time spent in Pilot.Action
+	MissedMoves  int               `json:"missed_moves"`     // Ticks an external program did not answer in time
+	Error        string            `json:"error,omitempty"`  // Why an external program failed, flown with the engines off from then on
+}
+
+// RunEpisode plays one episode from the start state without rendering.
+func RunEpisode(pilot Pilot, start *GameState, maxTicks int) EpisodeResult {
+	return RecordEpisode(pilot, start, maxTicks, nil)
+}
+
+// RecordEpisode plays one episode like RunEpisode, recording every tick into replay unless it is nil.
+func RecordEpisode(pilot Pilot, start *GameState, maxTicks int, replay *Replay) EpisodeResult {
+	state := start
+	result := EpisodeResult{}
+
+	for result.Ticks < maxTicks && !state.IsDone() {
+		state.ground().Update()
+		start := time.Now()
+		action := pilot.Action(state.Copy())
+		result.DecisionTime += time.Since(start)
+
+		controls := ActionControls(action)
+		next := state.Step(action)
+		reward := ScoreReward.Reward(state, next, controls)
+		if replay != nil {
+			replay.Record(action, state.FuelCutoff(controls), state.ground().Wind.Force(), reward, next)
+		}
+		result.Score += reward
+		state = next
+		result.Ticks++
+	}
+
+	result.Reason = state.Reason
+	result.Truncated = !state.IsDone()
+	result.FuelUsed = state.FuelUsed
+	return result
+}
+
+// BatchStats aggregates the results of many episodes.
+type BatchStats struct {
+	Agent            string                    `json:"agent"`
+	Seed             int64                     `json:"seed"`
+	Episodes         int                       `json:"episodes"`
+	Landed           int                       `json:"landed"`
+	LandingRate      float64                   `json:"landing_rate"`
+	Crashes          map[TerminationReason]int `json:"crashes"`
+	Truncated        int                       `json:"truncated"`
+	MeanScore        float64                   `json:"mean_score"`
+	MedianScore      float64                   `json:"median_score"`
+	MeanTicksToLand  float64                   `json:"mean_ticks_to_land"`
+	MeanFuelUsed     float64                   `json:"mean_fuel_used"`
+	MeanFuelToLand   float64                   `json:"mean_fuel_to_land"`
+	MeanDecisionTime time.Duration             `json:"mean_decision_time_ns"`
+	MissedMoves      int                       `json:"missed_moves"` // Ticks external programs did not answer in time
+	Failed           int                       `json:"failed"`       // Episodes in which an external program failed
+}
+
+// Summarize computes batch statistics over episode results.
+func Summarize(results []EpisodeResult) BatchStats {
+	stats := BatchStats{Episodes: len(results), Crashes: map[TerminationReason]int{}}
+	if len(results) == 0 {
+		return stats
+	}
+
+	scores := make([]float64, 0, len(results))
+	totalScore := 0.0
+	totalFuel := 0.0
+	landedFuel := 0.0
+	landedTicks := 0
+	decisions := 0
+	var decisionTime time.Duration
+	for _, r := range results {
+		switch {
+		case r.Truncated:
+			stats.Truncated++
+		case r.Reason == Landed:
+			stats.Landed++
+			landedTicks += r.Ticks
+			landedFuel += r.FuelUsed
+		default:
+			stats.Crashes[r.Reason]++
+		}
+		scores = append(scores, r.Score)
+		totalScore += r.Score
+		totalFuel += r.FuelUsed
+		decisions += r.Ticks
+		decisionTime += r.DecisionTime
+		stats.MissedMoves += r.MissedMoves
+		if r.Error != "" {
+			stats.Failed++
+		}
+	}
+
+	stats.LandingRate = float64(stats.Landed) / float64(len(results))
+	stats.MeanScore = totalScore / float64(len(results))
+	stats.MedianScore = median(scores)
+	stats.MeanFuelUsed = totalFuel / float64(len(results))
+	if stats.Landed > 0 {
+		stats.MeanTicksToLand = float64(landedTicks) / float64(stats.Landed)
+		stats.MeanFuelToLand = landedFuel / float64(stats.Landed)
+	}
+	if decisions > 0 {
+		stats.MeanDecisionTime = decisionTime / time.Duration(decisions)
+	}
+	return stats
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// WriteText prints a human readable summary.
+func (s BatchStats) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w,
+		"Agent: %s (seed %d)\nEpisodes: %d\nLanding rate: %.1f%% (%d)\nCrashes: %d, into terrain: %d, out of bounds: %d\nTruncated: %d\nScore: mean %.2f, median %.2f\nTicks to land: %.1f\nFuel used: mean %.1f, to land %.1f\nTime per decision: %v\nMissed moves: %d, failed programs: %d\n",
+		s.Agent, s.Seed, s.Episodes, s.LandingRate*100, s.Landed,
+		s.Crashes[Crashed], s.Crashes[CrashedTerrain], s.Crashes[OutOfBounds], s.Truncated,
+		s.MeanScore, s.MedianScore, s.MeanTicksToLand, s.MeanFuelUsed, s.MeanFuelToLand, s.MeanDecisionTime, s.MissedMoves, s.Failed,
+	)
+	return err
+}
+
+// WriteJSON writes the summary together with every episode.
+func (s BatchStats) WriteJSON(w io.Writer, results []EpisodeResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Summary  BatchStats      `json:"summary"`
+		Episodes []EpisodeResult `json:"episodes"`
+	}{s, results})
+}
+
+// WriteCSV writes one row per episode.
+func (s BatchStats) WriteCSV(w io.Writer, results []EpisodeResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"agent", "seed", "episode", "episode_seed", "level", "reason", "truncated", "score", "ticks", "fuel_used", "decision_time_ns", "missed_moves", "error"})
+	for _, r := range results {
+		writer.Write([]string{
+			s.Agent,
+			strconv.FormatInt(s.Seed, 10),
+			strconv.Itoa(r.Episode),
+			strconv.FormatInt(r.Seed, 10),
+			strconv.FormatInt(r.Level, 10),
+			r.Reason.String(),
+			strconv.FormatBool(r.Truncated),
+			strconv.FormatFloat(r.Score, 'f', -1, 64),
+			strconv.Itoa(r.Ticks),
+			strconv.FormatFloat(r.FuelUsed, 'f', -1, 64),
+			strconv.FormatInt(int64(r.DecisionTime), 10),
+			strconv.Itoa(r.MissedMoves),
+			r.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// runCommand implements `lander run`, playing episodes headlessly and printing statistics.
+func runCommand(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	agentName := flags.String("agent", "mcts", "pilot to evaluate: mcts, random, heuristic, idle or process")
+	episodes := flags.Int("episodes", 100, "number of episodes to play")
+	seed := flags.Int64("seed", 1, "random seed")
+	maxTicks := flags.Int("max-ticks", 1000, "tick limit per episode")
+	format := flags.String("format", "text", "output format: text, json or csv")
+	record := flags.String("record", "", "directory to save a replay of every episode to")
+	agentConfig := agentFlags(flags, DefaultAgentConfig())
+	processConfig := processFlags(flags)
+	level := terrainFlags(flags)
+	startDistribution := startFlags(flags)
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if *format != "text" && *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q (want text, json or csv)", *format)
+	}
+	config, err := agentConfig()
+	if err != nil {
+		return err
+	}
+	process := processConfig()
+	// An external program is started for each episode, so it cannot carry anything over
+	newPilot := func(seed int64) (Pilot, error) {
+		if *agentName == "process" {
+			return StartProcessPilot(process, seed)
+		}
+		return NewPilot(*agentName, seed, config)
+	}
+	if *agentName == "process" {
+		if len(process.Command) == 0 {
+			return errors.New("the process agent needs a program (set --agent-command)")
+		}
+	} else if _, err := NewPilot(*agentName, *seed, config); err != nil {
+		return err
+	}
+
+	results := make([]EpisodeResult, 0, *episodes)
+	start := startDistribution()
+	for i := 0; i < *episodes; i++ {
+		// Every episode has its own seed for the start, the pilot and, with random terrain, the level,
+		// so any episode plays out the same again on its own
+		episodeSeed := *seed + int64(i)
+		env, err := level(episodeSeed)
+		if err != nil {
+			return err
+		}
+		pilot, err := newPilot(episodeSeed)
+		if err != nil {
+			return err
+		}
+		state := start.Sample(episodeSeed, env)
+		var replay *Replay
+		if *record != "" {
+			replay = NewReplay(episodeSeed, *agentName, flagConfig(flags), state)
+		}
+		result := RecordEpisode(pilot, state, *maxTicks, replay)
+		if process, ok := pilot.(*ProcessPilot); ok {
+			// A failing program loses its episode, the others are still played
+			if err := process.Close(); err != nil {
+				result.Error = err.Error()
+			}
+			result.MissedMoves = process.MissedMoves
+		}
+		result.Episode = i
+		result.Seed = episodeSeed
+		result.Level = env.Seed
+		if replay != nil {
+			if _, err := SaveReplay(*record, replay); err != nil {
+				return err
+			}
+		}
+		results = append(results, result)
+	}
+
+	stats := Summarize(results)
+	stats.Agent = *agentName
+	stats.Seed = *seed
+
+	switch *format {
+	case "json":
+		return stats.WriteJSON(os.Stdout, results)
+	case "csv":
+		return stats.WriteCSV(os.Stdout, results)
+	}
+	return stats.WriteText(os.Stdout)
+}
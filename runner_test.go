@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunEpisode(t *testing.T) {
+	// Test case 1: Doing nothing falls onto the pad too fast
+	idle := PilotFunc(func(*GameState) int { return 0 })
+	result := RunEpisode(idle, StartDistribution{}.Sample(0, NewEnvironment()), 1000)
+	if result.Reason != Crashed || result.Truncated {
+		t.Errorf("Expected an idle lander to crash, but got %+v", result)
+	}
+	if result.Score >= 0 || result.Ticks == 0 {
+		t.Errorf("Expected a negative score after some ticks, but got %+v", result)
+	}
+
+	// Test case 2: Hovering runs into the tick limit
+	hover := PilotFunc(func(s *GameState) int {
+		if s.VelocityY > 0 {
+			return 2
+		}
+		return 0
+	})
+	result = RunEpisode(hover, StartDistribution{}.Sample(0, NewEnvironment()), 50)
+	if !result.Truncated || result.Ticks != 50 {
+		t.Errorf("Expected a truncated episode after 50 ticks, but got %+v", result)
+	}
+}
+
+func TestRunEpisodeReproducible(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.Iterations = 30
+	config.RolloutDepth = 20
+	config.Rollout = EpsilonPolicy{Epsilon: 0.5, Policy: HeuristicPolicy{}}
+	config.OpenLoop = true
+	config.Workers = 3
+	play := func(seed int64) EpisodeResult {
+		env, err := GenerateTerrain(DefaultTerrainConfig(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Wind = Wind{Turbulence: 0.01, Seed: seed}
+		pilot, err := NewPilot("mcts", seed, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := RunEpisode(pilot, DefaultStartDistribution().Sample(seed, env), 60)
+		result.DecisionTime = 0
+		return result
+	}
+
+	// Everything random is drawn from the seed, so the episode repeats exactly
+	for _, parallelism := range []Parallelism{Sequential, RootParallel, LeafParallel} {
+		config.Parallelism = parallelism
+		if first, again := play(3), play(3); first != again {
+			t.Errorf("%s: expected the same episode twice, but got %+v and %+v", parallelism, first, again)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []EpisodeResult{
+		{Reason: Landed, Score: 100, Ticks: 200, FuelUsed: 20, DecisionTime: 200 * time.Millisecond},
+		{Reason: Landed, Score: 100, Ticks: 300, FuelUsed: 40, DecisionTime: 300 * time.Millisecond},
+		{Reason: Crashed, Score: -100, Ticks: 100, DecisionTime: 100 * time.Millisecond, MissedMoves: 3, Error: "tick 5: invalid action"},
+		{Reason: CrashedTerrain, Score: -100, Ticks: 100, DecisionTime: 100 * time.Millisecond},
+		{Reason: NotTerminated, Truncated: true, Score: 0, Ticks: 300, DecisionTime: 300 * time.Millisecond},
+	}
+	stats := Summarize(results)
+
+	if stats.Landed != 2 || stats.LandingRate != 0.4 {
+		t.Errorf("Expected 2 landings (40%%), but got %d (%v)", stats.Landed, stats.LandingRate)
+	}
+	if stats.Crashes[Crashed] != 1 || stats.Crashes[CrashedTerrain] != 1 || stats.Truncated != 1 {
+		t.Errorf("Unexpected crash breakdown: %v, truncated %d", stats.Crashes, stats.Truncated)
+	}
+	if stats.MeanScore != 0 || stats.MedianScore != 0 {
+		t.Errorf("Expected mean and median score 0, but got %v and %v", stats.MeanScore, stats.MedianScore)
+	}
+	if stats.MeanTicksToLand != 250 {
+		t.Errorf("Expected 250 ticks to land, but got %v", stats.MeanTicksToLand)
+	}
+	if stats.MeanFuelUsed != 12 || stats.MeanFuelToLand != 30 {
+		t.Errorf("Expected 12 fuel per episode and 30 per landing, but got %v and %v", stats.MeanFuelUsed, stats.MeanFuelToLand)
+	}
+	if stats.MeanDecisionTime != time.Millisecond {
+		t.Errorf("Expected 1ms per decision, but got %v", stats.MeanDecisionTime)
+	}
+	if stats.MissedMoves != 3 || stats.Failed != 1 {
+		t.Errorf("Expected 3 missed moves and 1 failed program, but got %d and %d", stats.MissedMoves, stats.Failed)
+	}
+}
+
+func TestBatchStatsOutput(t *testing.T) {
+	results := []EpisodeResult{{Episode: 0, Seed: 7, Level: 3, Reason: CrashedTerrain, Score: -100, Ticks: 42, FuelUsed: 3.5}}
+	stats := Summarize(results)
+	stats.Agent = "idle"
+
+	var buf bytes.Buffer
+	if err := stats.WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"reason": "Crashed into Terrain"`) {
+		t.Errorf("Expected JSON to name the reason, but got %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := stats.WriteCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[1] != "idle,0,0,7,3,Crashed into Terrain,false,-100,42,3.5,0,0," {
+		t.Errorf("Unexpected CSV output: %q", lines)
+	}
+}
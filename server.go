@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+)
+
+// serverRequest is a line sent by a client. Cmd is reset, step, observe, render, spaces or close.
+type serverRequest struct {
+	Cmd    string `json:"cmd"`
+	Seed   int64  `json:"seed"`   // Seed of the episode to reset to
+	Action int    `json:"action"` // Action to step with
+}
+
+// serverResponse is the line answering a request, with the fields the command fills in.
+type serverResponse struct {
+	Error            string       `json:"error,omitempty"`
+	Observation      *Observation `json:"observation,omitempty"`
+	Reward           float64      `json:"reward"`
+	Terminated       bool         `json:"terminated"`
+	Truncated        bool         `json:"truncated"`
+	Info             *Info        `json:"info,omitempty"`
+	PNG              []byte       `json:"png,omitempty"` // Base64 in JSON
+	ActionSpace      *Discrete    `json:"action_space,omitempty"`
+	ObservationSpace *Box         `json:"observation_space,omitempty"`
+}
+
+// Server exposes environments to external agents over a JSON-lines protocol.
+// Every connection is a session with its own environment, reset with its own seeds.
+type Server struct {
+	NewEnv func() *LanderEnv // Creates the environment of a new session
+	Logf   func(format string, args ...any)
+}
+
+// Serve accepts sessions on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		go s.serveSession(conn)
+	}
+}
+
+// serveSession answers the requests of one connection in order until the client closes it.
+func (s *Server) serveSession(conn net.Conn) {
+	defer conn.Close()
+	env := s.NewEnv()
+	started := false
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	writer := bufio.NewWriter(conn)
+	encoder := json.NewEncoder(writer)
+
+	for {
+		var req serverRequest
+		err := decoder.Decode(&req)
+		if err == io.EOF || errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			// The stream is out of step after a malformed line, so the session ends
+			encoder.Encode(serverResponse{Error: fmt.Sprintf("bad request: %v", err)})
+			writer.Flush()
+			s.logf("%s: %v", conn.RemoteAddr(), err)
+			return
+		}
+
+		resp := serverResponse{}
+		switch req.Cmd {
+		case "reset":
+			obs, info := env.Reset(req.Seed)
+			resp.Observation, resp.Info = &obs, &info
+			started = true
+		case "step":
+			if !started {
+				resp.Error = "reset the environment first"
+			} else if !env.ActionSpace().Contains(req.Action) {
+				resp.Error = fmt.Sprintf("action %d outside the action space", req.Action)
+			} else {
+				obs, reward, terminated, truncated, info := env.Step(req.Action)
+				resp.Observation, resp.Reward, resp.Terminated, resp.Truncated, resp.Info = &obs, reward, terminated, truncated, &info
+			}
+		case "observe":
+			if !started {
+				resp.Error = "reset the environment first"
+			} else {
+				obs, info := env.Observe()
+				resp.Observation, resp.Info = &obs, &info
+			}
+		case "render":
+			var buf bytes.Buffer
+			if !started {
+				resp.Error = "reset the environment first"
+			} else if err := RenderPNG(&buf, env.State()); err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.PNG = buf.Bytes()
+			}
+		case "spaces":
+			actions, observations := env.ActionSpace(), env.ObservationSpace()
+			resp.ActionSpace, resp.ObservationSpace = &actions, &observations
+		case "close":
+			encoder.Encode(resp)
+			writer.Flush()
+			return
+		default:
+			resp.Error = fmt.Sprintf("unknown command %q (want reset, step, observe, render, spaces or close)", req.Cmd)
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// serveCommand implements `lander serve`, running the server until interrupted.
+func serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	network := flags.String("network", "tcp", "socket type: tcp or unix")
+	address := flags.String("addr", "127.0.0.1:5555", "address to listen on, a host:port or a socket file")
+	maxTicks := flags.Int("max-ticks", 1000, "tick limit per episode")
+	level := terrainFlags(flags)
+	startDistribution := startFlags(flags)
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if *network != "tcp" && *network != "unix" {
+		return fmt.Errorf("unknown network %q (want tcp or unix)", *network)
+	}
+	if _, err := level(0); err != nil {
+		return err
+	}
+	server := &Server{
+		NewEnv: func() *LanderEnv {
+			env := NewLanderEnv()
+			env.Start = startDistribution()
+			env.MaxTicks = *maxTicks
+			env.Level = func(seed int64) *Environment {
+				e, _ := level(seed) // Checked above
+				return e
+			}
+			return env
+		},
+		Logf: log.Printf,
+	}
+
+	listener, err := net.Listen(*network, *address)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		listener.Close() // Removes a unix socket file
+	}()
+	log.Printf("Listening on %s %s", *network, listener.Addr())
+	return server.Serve(listener)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"image/png"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"LunarLanderMonteCarloTreeSearch/client"
+)
+
+// startServer serves environments with the given settings on a loopback listener.
+func startServer(t *testing.T, network, address string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{NewEnv: NewLanderEnv, Logf: t.Logf}
+	go server.Serve(listener)
+	t.Cleanup(func() { listener.Close() })
+	return listener
+}
+
+func TestServerSessions(t *testing.T) {
+	listener := startServer(t, "tcp", "127.0.0.1:0")
+
+	// Every session plays its own seed, scored as the environment scores it directly
+	var wg sync.WaitGroup
+	for seed := int64(1); seed <= 4; seed++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			c, err := client.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer c.Close()
+
+			env := NewLanderEnv()
+			want, _ := env.Reset(seed)
+			obs, info, err := c.Reset(seed)
+			if err != nil || obs != client.Observation(want) || info.Seed != seed {
+				t.Errorf("Seed %d: expected the start %v, but got %v (%v)", seed, want, obs, err)
+				return
+			}
+			var last client.Observation
+			for {
+				wantObs, wantReward, terminated, _, _ := env.Step(0)
+				step, err := c.Step(0)
+				if err != nil || step.Observation != client.Observation(wantObs) || step.Reward != wantReward {
+					t.Errorf("Seed %d, tick %d: expected %v scoring %v, but got %+v (%v)", seed, step.Info.Tick, wantObs, wantReward, step, err)
+					return
+				}
+				if terminated != step.Terminated {
+					t.Errorf("Seed %d: expected terminated %v, but got %v", seed, terminated, step.Terminated)
+					return
+				}
+				last = step.Observation
+				if terminated {
+					break
+				}
+			}
+			if obs, info, err := c.Observe(); err != nil || info.Reason != Crashed.String() || obs != last {
+				t.Errorf("Seed %d: expected to observe the crash at %v, but got %v with %+v (%v)", seed, last, obs, info, err)
+			}
+		}(seed)
+	}
+	wg.Wait()
+}
+
+func TestServerRequests(t *testing.T) {
+	listener := startServer(t, "tcp", "127.0.0.1:0")
+	c, err := client.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Test case 1: Nothing to step, observe or render before a reset
+	if _, err := c.Step(0); err == nil {
+		t.Errorf("Expected an error stepping before a reset")
+	}
+	if _, err := c.Render(); err == nil {
+		t.Errorf("Expected an error rendering before a reset")
+	}
+
+	// Test case 2: The spaces match the environment's
+	spaces, err := c.Spaces()
+	want := NewLanderEnv().ObservationSpace()
+	if err != nil || spaces.Actions != 4 || len(spaces.Low) != len(want.Low) || spaces.High[ObsAngle] != want.High[ObsAngle] {
+		t.Errorf("Expected 4 actions and the observation bounds %+v, but got %+v (%v)", want, spaces, err)
+	}
+
+	// Test case 3: Invalid actions are refused, the session goes on
+	c.Reset(1)
+	if _, err := c.Step(7); err == nil || !strings.Contains(err.Error(), "action 7") {
+		t.Errorf("Expected an error for action 7, but got %v", err)
+	}
+	if step, err := c.Step(2); err != nil || step.Info.Tick != 1 {
+		t.Errorf("Expected the first tick, but got %+v (%v)", step, err)
+	}
+
+	// Test case 4: Rendering returns a PNG of the screen
+	data, err := c.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil || img.Bounds().Dx() != ScreenWidth || img.Bounds().Dy() != ScreenHeight {
+		t.Errorf("Expected a %dx%d PNG, but got %v (%v)", ScreenWidth, ScreenHeight, img.Bounds(), err)
+	}
+}
+
+func TestServerProtocol(t *testing.T) {
+	listener := startServer(t, "unix", filepath.Join(t.TempDir(), "lander.sock"))
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	lines := bufio.NewScanner(conn)
+	send := func(line string) string {
+		if _, err := conn.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		if !lines.Scan() {
+			t.Fatalf("No response to %s: %v", line, lines.Err())
+		}
+		return lines.Text()
+	}
+
+	// Test case 1: One JSON line answers each request
+	if resp := send(`{"cmd": "reset", "seed": 3}`); !strings.Contains(resp, `"observation":[`) || !strings.Contains(resp, `"seed":3`) {
+		t.Errorf("Expected an observation from seed 3, but got %s", resp)
+	}
+	if resp := send(`{"cmd": "fly"}`); !strings.Contains(resp, `"error":"unknown command \"fly\"`) {
+		t.Errorf("Expected an unknown command error, but got %s", resp)
+	}
+
+	// Test case 2: A malformed line ends the session
+	if resp := send(`{"cmd": ` + "\n}}"); !strings.Contains(resp, `"error":"bad request`) {
+		t.Errorf("Expected a bad request error, but got %s", resp)
+	}
+	if lines.Scan() {
+		t.Errorf("Expected the session to end, but got %s", lines.Text())
+	}
+}
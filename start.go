@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"math/rand"
+)
+
+// StartDistribution describes how episodes start: around the top center of the screen,
+// pushed by a random initial force and slightly tilted.
+type StartDistribution struct {
+	XSpread  float64 // Start X is up to this far either side of StartX
+	YSpread  float64 // Start Y is up to this far below StartY
+	Velocity float64 // Initial velocity is up to this fast either way along each axis
+	Angle    float64 // Initial tilt is up to this many radians either way
+}
+
+// DefaultStartDistribution varies the start enough to matter while leaving room to reach the pad.
+func DefaultStartDistribution() StartDistribution {
+	return StartDistribution{
+		XSpread:  100,
+		YSpread:  50,
+		Velocity: 1,
+		Angle:    0.1,
+	}
+}
+
+// Sample draws the start state of an episode over env. The same seed always gives the
+// same start, the zero StartDistribution always starts at rest at StartX, StartY.
+func (d StartDistribution) Sample(seed int64, env *Environment) *GameState {
+	rng := rand.New(rand.NewSource(seed))
+	spread := func(width float64) float64 {
+		return (2*rng.Float64() - 1) * width
+	}
+	return &GameState{
+		LanderX:   StartX + spread(d.XSpread),
+		LanderY:   StartY + rng.Float64()*d.YSpread,
+		VelocityX: spread(d.Velocity),
+		VelocityY: spread(d.Velocity),
+		Angle:     spread(d.Angle),
+		Terrain:   env,
+	}
+}
+
+// startFlags registers the start state settings on a flag set.
+// The returned function builds the distribution once the flags are parsed.
+func startFlags(flags *flag.FlagSet) func() StartDistribution {
+	defaults := DefaultStartDistribution()
+	xSpread := flags.Float64("start-x-spread", defaults.XSpread, "start up to this far either side of the top center, 0 for a fixed start")
+	ySpread := flags.Float64("start-y-spread", defaults.YSpread, "start up to this far below the top of the screen")
+	velocity := flags.Float64("start-velocity", defaults.Velocity, "initial velocity up to this fast along each axis")
+	angle := flags.Float64("start-angle", defaults.Angle, "initial tilt up to this many radians either way")
+
+	return func() StartDistribution {
+		return StartDistribution{XSpread: *xSpread, YSpread: *ySpread, Velocity: *velocity, Angle: *angle}
+	}
+}
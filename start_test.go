@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStartDistributionSample(t *testing.T) {
+	env := NewEnvironment()
+	d := DefaultStartDistribution()
+
+	// Test case 1: The same seed gives the same start
+	if a, b := d.Sample(5, env), d.Sample(5, env); *a != *b {
+		t.Errorf("Expected the same start for the same seed, but got %+v and %+v", a, b)
+	}
+
+	// Test case 2: Starts vary within the configured ranges
+	distinct := map[float64]bool{}
+	for seed := int64(0); seed < 100; seed++ {
+		s := d.Sample(seed, env)
+		distinct[s.LanderX] = true
+		if math.Abs(s.LanderX-StartX) > d.XSpread || s.LanderY < StartY || s.LanderY > StartY+d.YSpread ||
+			math.Abs(s.VelocityX) > d.Velocity || math.Abs(s.VelocityY) > d.Velocity || math.Abs(s.Angle) > d.Angle {
+			t.Errorf("Seed %d: start out of range: %+v", seed, s)
+		}
+		if s.Terrain != env || s.IsDone() {
+			t.Errorf("Seed %d: expected a flying start over the level, but got %+v", seed, s)
+		}
+	}
+	if len(distinct) < 100 {
+		t.Errorf("Expected every seed to start somewhere else, but got %d places", len(distinct))
+	}
+
+	// Test case 3: The zero distribution starts at rest at the top center
+	if s := (StartDistribution{}).Sample(5, env); *s != (GameState{LanderX: StartX, LanderY: StartY, Terrain: env}) {
+		t.Errorf("Expected a fixed start, but got %+v", s)
+	}
+}
+
+func TestHeuristicLandsFromRandomStarts(t *testing.T) {
+	landed := 0
+	for seed := int64(1); seed <= 20; seed++ {
+		result := RunEpisode(PolicyPilot{Policy: HeuristicPolicy{}}, DefaultStartDistribution().Sample(seed, NewEnvironment()), 2000)
+		if result.Reason == Landed {
+			landed++
+		}
+	}
+	if landed < 15 {
+		t.Errorf("Expected the heuristic to land from most starts, but it landed %d of 20", landed)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// TerrainConfig describes a generated level.
+type TerrainConfig struct {
+	Seed       int64   // Same seed, same level
+	Segments   int     // Ground line segments across the screen, rounded up to a power of two
+	Roughness  float64 // How much of the displacement survives each halving, 0 smooth to 1 jagged
+	PeakHeight float64 // Height of the highest peak above the pad
+	PadX       float64 // Center of the landing pad
+	PadWidth   float64 // Width of the landing pad
+}
+
+// DefaultTerrainConfig is a level about as rough as the classic map, with the pad in the same place.
+func DefaultTerrainConfig(seed int64) TerrainConfig {
+	return TerrainConfig{
+		Seed:       seed,
+		Segments:   64,
+		Roughness:  0.55,
+		PeakHeight: 120,
+		PadX:       (LandingPadLeft + LandingPadRight) / 2,
+		PadWidth:   LandingPadRight - LandingPadLeft,
+	}
+}
+
+// Validate checks that the landing pad has a width and lies on the screen.
+func (c TerrainConfig) Validate() error {
+	if c.PadWidth <= 0 {
+		return fmt.Errorf("pad width %v must be positive", c.PadWidth)
+	}
+	if padLeft, padRight := c.PadX-c.PadWidth/2, c.PadX+c.PadWidth/2; padLeft < 0 || padRight > ScreenWidth {
+		return fmt.Errorf("pad from %v to %v does not fit on the screen (0 to %d)", padLeft, padRight, ScreenWidth)
+	}
+	return nil
+}
+
+// GenerateTerrain builds a level by midpoint displacement: starting from the two screen
+// edges, each segment's midpoint is moved by a random amount that shrinks by Roughness
+// at every level of subdivision. The ground is then scaled to PeakHeight and flattened
+// at GroundLevel under the landing pad. Configurations that fail Validate are rejected.
+func GenerateTerrain(config TerrainConfig) (*Environment, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+
+	segments := 1
+	for segments < config.Segments {
+		segments *= 2
+	}
+	heights := make([]float64, segments+1)
+	heights[0] = rng.Float64()
+	heights[segments] = rng.Float64()
+	displacement := 1.0
+	for step := segments; step > 1; step /= 2 {
+		for i := step / 2; i < segments; i += step {
+			heights[i] = (heights[i-step/2]+heights[i+step/2])/2 + (rng.Float64()*2-1)*displacement
+		}
+		displacement *= config.Roughness
+	}
+
+	// Scale so the lowest point is at pad level and the highest PeakHeight above it
+	low, high := heights[0], heights[0]
+	for _, h := range heights {
+		low = math.Min(low, h)
+		high = math.Max(high, h)
+	}
+	scale := 0.0
+	if high > low {
+		scale = config.PeakHeight / (high - low)
+	}
+
+	padLeft, padRight := config.PadX-config.PadWidth/2, config.PadX+config.PadWidth/2
+	ground := make([]Point, 0, segments+3)
+	add := func(p Point) {
+		// The ground must move right, a vertical step would make HeightAt divide by zero
+		if len(ground) == 0 || p.X > ground[len(ground)-1].X {
+			ground = append(ground, p)
+		}
+	}
+	padAdded := false
+	for i, h := range heights {
+		x := float64(ScreenWidth) * float64(i) / float64(segments)
+		if x >= padLeft && x <= padRight {
+			continue
+		}
+		if x > padRight && !padAdded {
+			add(Point{padLeft, GroundLevel})
+			add(Point{padRight, GroundLevel})
+			padAdded = true
+		}
+		add(Point{x, GroundLevel - (h-low)*scale})
+	}
+	if !padAdded {
+		add(Point{padLeft, GroundLevel})
+		add(Point{padRight, GroundLevel})
+	}
+
+	return &Environment{
+		Ground:      ground,
+		TargetX:     config.PadX,
+		TargetY:     GroundLevel,
+		TargetWidth: config.PadWidth,
+		Seed:        config.Seed,
+	}, nil
+}
+
+// terrainFlags registers the level settings on a flag set.
+// The returned function builds the level for a seed once the flags are parsed.
+func terrainFlags(flags *flag.FlagSet) func(seed int64) (*Environment, error) {
+	defaults := DefaultTerrainConfig(0)
+	terrain := flags.String("terrain", "classic", "level: classic map or random generated terrain")
+	roughness := flags.Float64("roughness", defaults.Roughness, "random terrain roughness, 0 smooth to 1 jagged")
+	peakHeight := flags.Float64("peak-height", defaults.PeakHeight, "random terrain peak height above the pad")
+	padX := flags.Float64("pad-x", defaults.PadX, "random terrain landing pad center")
+	padWidth := flags.Float64("pad-width", defaults.PadWidth, "random terrain landing pad width")
+	wind := flags.Float64("wind", 0, "steady wind force per tick, positive blows to the right")
+	turbulence := flags.Float64("turbulence", 0, "typical strength of the wind gusts")
+
+	return func(seed int64) (*Environment, error) {
+		var env *Environment
+		switch *terrain {
+		case "classic":
+			env = NewEnvironment()
+		case "random":
+			config := DefaultTerrainConfig(seed)
+			config.Roughness = *roughness
+			config.PeakHeight = *peakHeight
+			config.PadX = *padX
+			config.PadWidth = *padWidth
+			var err error
+			if env, err = GenerateTerrain(config); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown terrain %q (want classic or random)", *terrain)
+		}
+		env.Wind = Wind{Bias: *wind, Turbulence: *turbulence, Seed: seed}
+		return env, nil
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateTerrain(t *testing.T) {
+	config := DefaultTerrainConfig(42)
+	config.PadX = 600
+	config.PadWidth = 120
+	env, err := GenerateTerrain(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test case 1: Same seed, same level; other seed, other level
+	if again, _ := GenerateTerrain(config); !reflect.DeepEqual(env.Ground, again.Ground) {
+		t.Errorf("Expected the same seed to generate the same level")
+	}
+	config.Seed = 43
+	if other, _ := GenerateTerrain(config); reflect.DeepEqual(env.Ground, other.Ground) {
+		t.Errorf("Expected another seed to generate another level")
+	}
+
+	// Test case 2: The ground spans the screen, stays within the peak height and moves left to right
+	if env.Ground[0].X != 0 || env.Ground[len(env.Ground)-1].X != ScreenWidth {
+		t.Errorf("Expected the ground to span the screen, but it runs from %v to %v", env.Ground[0].X, env.Ground[len(env.Ground)-1].X)
+	}
+	for i, p := range env.Ground {
+		if p.Y > GroundLevel || p.Y < GroundLevel-config.PeakHeight-1e-9 {
+			t.Errorf("Point %d at height %v is outside the terrain band", i, p.Y)
+		}
+		if i > 0 && p.X <= env.Ground[i-1].X {
+			t.Errorf("Point %d does not move right: %v after %v", i, p.X, env.Ground[i-1].X)
+		}
+	}
+
+	// Test case 3: The pad is flat where it was asked for
+	if env.TargetX != 600 || env.TargetWidth != 120 || env.Seed != 42 {
+		t.Errorf("Unexpected pad or seed: %+v", env)
+	}
+	for x := 540.0; x <= 660; x += 10 {
+		if h := env.HeightAt(x); h != GroundLevel {
+			t.Errorf("Expected the pad at height %v, but got %v at x=%v", float64(GroundLevel), h, x)
+		}
+	}
+}
+
+func TestGenerateTerrainPads(t *testing.T) {
+	// Test case 1: Pads at the edges, across the screen or ending on a ground vertex leave a ground moving right
+	for _, pad := range [][2]float64{{60, 120}, {740, 120}, {400, ScreenWidth}, {100, 50}} {
+		config := DefaultTerrainConfig(1)
+		config.PadX, config.PadWidth = pad[0], pad[1]
+		env, err := GenerateTerrain(config)
+		if err != nil {
+			t.Fatalf("pad %v: %v", pad, err)
+		}
+		for i := 1; i < len(env.Ground); i++ {
+			if env.Ground[i].X <= env.Ground[i-1].X {
+				t.Errorf("pad %v: point %d does not move right: %v after %v", pad, i, env.Ground[i].X, env.Ground[i-1].X)
+			}
+		}
+		for x := 0.0; x <= ScreenWidth; x += 12.5 {
+			if h, s := env.HeightAt(x), env.SlopeAt(x); math.IsNaN(h) || math.IsInf(h, 0) || math.IsNaN(s) || math.IsInf(s, 0) {
+				t.Errorf("pad %v: height %v and slope %v at x=%v", pad, h, s, x)
+			}
+		}
+	}
+
+	// Test case 2: Pads without width or off the screen are rejected
+	for _, pad := range [][2]float64{{400, 0}, {400, -20}, {400, 1000}, {900, 100}, {-50, 100}} {
+		config := DefaultTerrainConfig(1)
+		config.PadX, config.PadWidth = pad[0], pad[1]
+		if _, err := GenerateTerrain(config); err == nil {
+			t.Errorf("pad %v: expected an error", pad)
+		}
+	}
+}
+
+func TestTerrainFlags(t *testing.T) {
+	// Test case 1: A pad inside the screen
+	level, err := parseTerrainFlags("-terrain", "random", "-pad-x", "100", "-pad-width", "200")
+	if err != nil || level.Ground[0].X != 0 || level.Ground[len(level.Ground)-1].X != ScreenWidth {
+		t.Errorf("Expected a level spanning the screen with the pad at the left edge (%v)", err)
+	}
+
+	// Test case 2: Pads with no width or reaching past the screen
+	for _, args := range [][]string{
+		{"-pad-width", "0"},
+		{"-pad-width", "-20"},
+		{"-pad-width", "1000"},
+		{"-pad-x", "900"},
+		{"-pad-x", "-50"},
+		{"-pad-x", "780", "-pad-width", "80"},
+	} {
+		if _, err := parseTerrainFlags(append([]string{"-terrain", "random"}, args...)...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+// parseTerrainFlags builds the level for seed 1 from command line arguments.
+func parseTerrainFlags(args ...string) (*Environment, error) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	level := terrainFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	return level(1)
+}
+
+func TestHeightAt(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct{ x, want float64 }{
+		{-50, GroundLevel},       // Left of the screen
+		{50, GroundLevel - 50},   // Halfway up the first peak
+		{100, GroundLevel - 100}, // On top of it
+		{400, GroundLevel},       // On the pad
+		{575, GroundLevel - 15},  // Halfway down the small right peak
+		{900, GroundLevel},       // Right of the screen
+	}
+	for _, tt := range tests {
+		if got := env.HeightAt(tt.x); got != tt.want {
+			t.Errorf("Expected height %v at x=%v, but got %v", tt.want, tt.x, got)
+		}
+	}
+}
+
+func TestSlopeAt(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct{ x, want float64 }{
+		{-50, 0},  // Left of the screen
+		{50, -1},  // Up the first peak
+		{100, -1}, // Its top belongs to the segment on the left
+		{150, 1},  // Down the other side
+		{400, 0},  // On the pad
+		{900, 0},  // Right of the screen
+	}
+	for _, tt := range tests {
+		if got := env.SlopeAt(tt.x); got != tt.want {
+			t.Errorf("Expected slope %v at x=%v, but got %v", tt.want, tt.x, got)
+		}
+	}
+}
+
+func TestHeuristicLandsOnGeneratedTerrain(t *testing.T) {
+	config := DefaultTerrainConfig(7)
+	config.PadX = 250
+	env, err := GenerateTerrain(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := RunEpisode(PolicyPilot{Policy: HeuristicPolicy{}}, StartDistribution{}.Sample(0, env), 2000)
+	if result.Reason != Landed {
+		t.Errorf("Expected the heuristic to find the moved pad, but got %+v", result)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// treeFormatVersion is bumped whenever the on-disk layout of a tree changes.
+const treeFormatVersion = 5
+
+// treeMagic starts every binary tree file.
+var treeMagic = [4]byte{'L', 'L', 'T', 'R'}
+
+// treeFile is the JSON layout of a saved tree.
+// Nodes are stored flat in pre-order, so a parent always comes before its children.
+type treeFile struct {
+	Version     int          `json:"version"`
+	Simulations int          `json:"simulations"`
+	Seed        int64        `json:"seed"`
+	Nodes       []nodeRecord `json:"nodes"`
+}
+
+// nodeRecord is a single node of a saved tree. Parent is an index into the node list, -1 for the root.
+type nodeRecord struct {
+	Parent      int       `json:"parent"`
+	Action      int       `json:"action"`
+	VisitCount  int       `json:"visit_count"`
+	TotalReward float64   `json:"total_reward"`
+	State       GameState `json:"state"`
+}
+
+// binaryNode is the fixed-size layout of a node in a binary tree file.
+type binaryNode struct {
+	Parent      int32
+	Action      int8
+	VisitCount  int64
+	TotalReward float64
+	LanderX     float64
+	LanderY     float64
+	VelocityX   float64
+	VelocityY   float64
+	Angle       float64
+	AngularVel  float64
+	FuelUsed    float64
+	LeftLeg     bool
+	RightLeg    bool
+	IsDoneFlag  bool
+	Reason      uint8
+}
+
+// binaryHeader starts a binary tree file.
+type binaryHeader struct {
+	Magic       [4]byte
+	Version     uint16
+	Simulations int64
+	Seed        int64
+	NodeCount   uint32
+}
+
+// records flattens the tree into pre-order node records.
+func (t *Tree) records() []nodeRecord {
+	var records []nodeRecord
+	var visit func(node *Node, parent int)
+	visit = func(node *Node, parent int) {
+		index := len(records)
+		records = append(records, nodeRecord{
+			Parent:      parent,
+			Action:      node.action,
+			VisitCount:  node.visitCount,
+			TotalReward: node.totalReward,
+			State:       *node.state,
+		})
+		for _, child := range node.children {
+			visit(child, index)
+		}
+	}
+	if t.Root != nil {
+		visit(t.Root, -1)
+	}
+	return records
+}
+
+// treeFromRecords rebuilds a tree, including parent links, from pre-order node records.
+func treeFromRecords(records []nodeRecord, simulations int, seed int64) (*Tree, error) {
+	if len(records) == 0 {
+		return nil, errors.New("tree has no nodes")
+	}
+	if records[0].Parent != -1 {
+		return nil, errors.New("first node is not the root")
+	}
+
+	nodes := make([]*Node, len(records))
+	for i, r := range records {
+		state := r.State
+		nodes[i] = &Node{
+			state:       &state,
+			action:      r.Action,
+			visitCount:  r.VisitCount,
+			totalReward: r.TotalReward,
+		}
+		if i == 0 {
+			continue
+		}
+		if r.Parent < 0 || r.Parent >= i {
+			return nil, fmt.Errorf("node %d has invalid parent %d", i, r.Parent)
+		}
+		parent := nodes[r.Parent]
+		nodes[i].parent = parent
+		parent.children = append(parent.children, nodes[i])
+	}
+	return &Tree{Root: nodes[0], Simulations: simulations, Seed: seed, nodes: len(nodes)}, nil
+}
+
+// writeTreeJSON encodes the tree in the versioned JSON format.
+func writeTreeJSON(w io.Writer, t *Tree) error {
+	return json.NewEncoder(w).Encode(treeFile{
+		Version:     treeFormatVersion,
+		Simulations: t.Simulations,
+		Seed:        t.Seed,
+		Nodes:       t.records(),
+	})
+}
+
+// readTreeJSON decodes a tree written by writeTreeJSON.
+func readTreeJSON(r io.Reader) (*Tree, error) {
+	var file treeFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Version != treeFormatVersion {
+		return nil, fmt.Errorf("unsupported tree format version %d", file.Version)
+	}
+	return treeFromRecords(file.Nodes, file.Simulations, file.Seed)
+}
+
+// writeTreeBinary encodes the tree in the compact little-endian binary format.
+func writeTreeBinary(w io.Writer, t *Tree) error {
+	records := t.records()
+	header := binaryHeader{
+		Magic:       treeMagic,
+		Version:     treeFormatVersion,
+		Simulations: int64(t.Simulations),
+		Seed:        t.Seed,
+		NodeCount:   uint32(len(records)),
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		node := binaryNode{
+			Parent:      int32(r.Parent),
+			Action:      int8(r.Action),
+			VisitCount:  int64(r.VisitCount),
+			TotalReward: r.TotalReward,
+			LanderX:     r.State.LanderX,
+			LanderY:     r.State.LanderY,
+			VelocityX:   r.State.VelocityX,
+			VelocityY:   r.State.VelocityY,
+			Angle:       r.State.Angle,
+			AngularVel:  r.State.AngularVelocity,
+			FuelUsed:    r.State.FuelUsed,
+			LeftLeg:     r.State.LeftLegOnGround,
+			RightLeg:    r.State.RightLegOnGround,
+			IsDoneFlag:  r.State.IsDoneFlag,
+			Reason:      uint8(r.State.Reason),
+		}
+		if err := binary.Write(w, binary.LittleEndian, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTreeBinary decodes a tree written by writeTreeBinary.
+func readTreeBinary(r io.Reader) (*Tree, error) {
+	var header binaryHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != treeMagic {
+		return nil, errors.New("not a binary tree file")
+	}
+	if header.Version != treeFormatVersion {
+		return nil, fmt.Errorf("unsupported tree format version %d", header.Version)
+	}
+
+	// The node count is not trusted for the allocation, a corrupt header runs out of records instead
+	records := make([]nodeRecord, 0, min(header.NodeCount, 1<<16))
+	for i := uint32(0); i < header.NodeCount; i++ {
+		var node binaryNode
+		if err := binary.Read(r, binary.LittleEndian, &node); err != nil {
+			return nil, fmt.Errorf("node %d of %d: %w", i, header.NodeCount, err)
+		}
+		records = append(records, nodeRecord{
+			Parent:      int(node.Parent),
+			Action:      int(node.Action),
+			VisitCount:  int(node.VisitCount),
+			TotalReward: node.TotalReward,
+			State: GameState{
+				LanderX:          node.LanderX,
+				LanderY:          node.LanderY,
+				VelocityX:        node.VelocityX,
+				VelocityY:        node.VelocityY,
+				Angle:            node.Angle,
+				AngularVelocity:  node.AngularVel,
+				FuelUsed:         node.FuelUsed,
+				LeftLegOnGround:  node.LeftLeg,
+				RightLegOnGround: node.RightLeg,
+				IsDoneFlag:       node.IsDoneFlag,
+				Reason:           TerminationReason(node.Reason),
+			},
+		})
+	}
+	return treeFromRecords(records, int(header.Simulations), header.Seed)
+}
+
+// treeCommand implements `lander tree FILE`, printing a summary of a saved tree.
+func treeCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: tree FILE.json|FILE.bin")
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	read := readTreeJSON
+	if strings.HasSuffix(args[0], ".bin") {
+		read = readTreeBinary
+	}
+	tree, err := read(bufio.NewReader(file))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	depth := 0
+	var measure func(node *Node, d int)
+	measure = func(node *Node, d int) {
+		depth = max(depth, d)
+		for _, child := range node.children {
+			measure(child, d+1)
+		}
+	}
+	measure(tree.Root, 0)
+
+	fmt.Printf("Nodes: %d, depth: %d, simulations: %d, seed: %d\n", len(tree.records()), depth, tree.Simulations, tree.Seed)
+	fmt.Printf("Root: visits %d, state %+v\n", tree.Root.visitCount, *tree.Root.state)
+	for _, child := range tree.Root.children {
+		fmt.Printf("  action %d: visits %d, mean reward %.2f\n",
+			child.action, child.visitCount, child.totalReward/float64(max(child.visitCount, 1)))
+	}
+	return nil
+}
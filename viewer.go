@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Timeline bar along the top of the replay viewer, click or drag it to scrub
+const (
+	timelineX      = 10
+	timelineY      = 10
+	timelineWidth  = ScreenWidth - 170
+	timelineHeight = 8
+)
+
+// ReplayViewer plays a recorded episode back in the window:
+// Space plays and pauses, Left/Right step a tick, Page Up/Down jump a second,
+// Home/End go to the start and end, Up/Down change the speed.
+type ReplayViewer struct {
+	replay    *Replay
+	position  float64 // Ticks played so far, fractional when playing slower than a tick per frame
+	speed     float64 // Ticks played per frame
+	playing   bool
+	scrubbing bool    // The timeline is being dragged
+	ghosts    []Ghost // Other replays flown alongside
+}
+
+// NewReplayViewer starts the replay paused at the start state, with ghosts flying alongside.
+func NewReplayViewer(replay *Replay, ghosts []Ghost) *ReplayViewer {
+	return &ReplayViewer{replay: replay, speed: 1, ghosts: ghosts}
+}
+
+// tick returns the tick on screen, 0 for the start state.
+func (v *ReplayViewer) tick() int {
+	return int(v.position)
+}
+
+// seek moves to tick, clamped to the replay.
+func (v *ReplayViewer) seek(tick int) {
+	v.position = float64(max(0, min(tick, len(v.replay.Ticks))))
+}
+
+// repeating reports a key press, repeating while the key is held down.
+func repeating(key ebiten.Key) bool {
+	const delay, interval = 15, 3
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || d > delay && d%interval == 0
+}
+
+func (v *ReplayViewer) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return ebiten.Termination
+	}
+
+	end := len(v.replay.Ticks)
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		v.playing = !v.playing
+		if v.playing && v.tick() == end {
+			v.seek(0) // Play again from the start
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		v.speed = math.Min(v.speed*2, 8)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		v.speed = math.Max(v.speed/2, 0.125)
+	}
+
+	// Stepping and jumping pause the playback
+	step := 0
+	switch {
+	case repeating(ebiten.KeyRight):
+		step = 1
+	case repeating(ebiten.KeyLeft):
+		step = -1
+	case repeating(ebiten.KeyPageDown):
+		step = ebiten.TPS()
+	case repeating(ebiten.KeyPageUp):
+		step = -ebiten.TPS()
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		step = -end
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		step = end
+	}
+	if step != 0 {
+		v.playing = false
+		v.seek(v.tick() + step)
+	}
+
+	// Drag along the timeline to scrub
+	x, y := ebiten.CursorPosition()
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) &&
+		x >= timelineX && x <= timelineX+timelineWidth && math.Abs(float64(y-timelineY-timelineHeight/2)) <= timelineHeight {
+		v.scrubbing = true
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		v.scrubbing = false
+	}
+	if v.scrubbing {
+		v.playing = false
+		v.seek(int(math.Round(float64(x-timelineX) / timelineWidth * float64(end))))
+	}
+
+	if v.playing {
+		v.position = math.Min(v.position+v.speed, float64(end))
+		if v.tick() == end {
+			v.playing = false
+		}
+	}
+	return nil
+}
+
+func (v *ReplayViewer) Draw(screen *ebiten.Image) {
+	tick := v.tick()
+	state := v.replay.State(tick)
+	current := ReplayTick{Action: -1}
+	if tick > 0 {
+		current = v.replay.Ticks[tick-1]
+	}
+
+	screen.Fill(color.Black)
+	v.replay.Header.Level.Draw(screen)
+	drawGhosts(screen, v.ghosts, tick)
+	lander := &Lander{}
+	lander.SetState(state)
+	lander.ThrustDown = boolToBit(current.Controls.Main)
+	lander.ThrustLeft = boolToBit(current.Controls.Left)
+	lander.ThrustRight = boolToBit(current.Controls.Right)
+	lander.Draw(screen)
+
+	playback := "Paused"
+	if v.playing {
+		playback = "Playing"
+	}
+	msg := fmt.Sprintf(
+		"X: %4.2f, Y: %4.2f\nVelX: %4.2f, VelY: %4.2f\nAngle: %4.2f, AngVel: %4.3f\nThrust: D:%d L:%d R:%d, Legs: L:%d R:%d\nPilot: %s (seed %d), action %d\nTick: %d/%d, %s at %gx\nScore: %4.2f (%+4.2f)",
+		state.LanderX, state.LanderY, state.VelocityX, state.VelocityY, state.Angle, state.AngularVelocity,
+		lander.ThrustDown, lander.ThrustLeft, lander.ThrustRight,
+		boolToBit(state.LeftLegOnGround), boolToBit(state.RightLegOnGround),
+		v.replay.Header.Pilot, v.replay.Header.Seed, current.Action,
+		tick, len(v.replay.Ticks), playback, v.speed, v.replay.Score(tick), current.Reward,
+	)
+	ebitenutil.DebugPrintAt(screen, msg, 0, 484)
+	drawFuelGauge(screen, state.Fuel()/FuelCapacity)
+	drawWindIndicator(screen, current.Wind)
+	v.drawTimeline(screen)
+
+	if state.Reason != NotTerminated {
+		ebitenutil.DebugPrintAt(screen, state.Reason.String(), 350, 300)
+	}
+}
+
+// drawTimeline draws the progress through the replay with a marker on the tick shown.
+func (v *ReplayViewer) drawTimeline(screen *ebiten.Image) {
+	progress := 0.0
+	if len(v.replay.Ticks) > 0 {
+		progress = float64(v.tick()) / float64(len(v.replay.Ticks))
+	}
+	ebitenutil.DrawRect(screen, timelineX, timelineY, timelineWidth, timelineHeight, color.Gray{64})
+	ebitenutil.DrawRect(screen, timelineX, timelineY, timelineWidth*progress, timelineHeight, color.Gray{160})
+	marker := timelineX + timelineWidth*progress
+	ebitenutil.DrawLine(screen, marker, timelineY-3, marker, timelineY+timelineHeight+3, color.White)
+	ebitenutil.DebugPrintAt(screen, "Space play, Left/Right step, Up/Down speed, Home/End", timelineX, timelineY+timelineHeight+4)
+}
+
+func (v *ReplayViewer) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Wind is a horizontal force on the flying lander: a steady bias plus turbulent gusts.
+// The gusts wander randomly and are pulled back toward zero, drawn from a generator
+// seeded with Seed, so a level blows the same way every time it is played.
+// The planner knows the wind blowing now and how gusts behave, but not the gusts to come.
+type Wind struct {
+	Bias       float64 // Steady force per tick, positive blows to the right
+	Turbulence float64 // Typical strength of the gusts, 0 for a steady wind
+	Seed       int64   // Same seed, same gusts
+
+	gust float64    // Current gust on top of the bias
+	rng  *rand.Rand // Draws the gusts, created from Seed on the first update
+}
+
+// Force returns the wind's current force on the lander.
+func (w Wind) Force() float64 {
+	return w.Bias + w.gust
+}
+
+// update moves the gusts on by one tick.
+func (w *Wind) update() {
+	if w.Turbulence == 0 {
+		return
+	}
+	if w.rng == nil {
+		w.rng = rand.New(rand.NewSource(w.Seed))
+	}
+	w.gust = w.nextGust(w.gust, w.rng.NormFloat64())
+}
+
+// reseed calms the gusts and draws them from seed from now on.
+func (w *Wind) reseed(seed int64) {
+	w.Seed, w.gust, w.rng = seed, 0, nil
+}
+
+// nextGust returns the gust blowing on the tick after gust, given a standard normal draw.
+// The draw is scaled so the gusts keep a standard deviation of Turbulence.
+func (w Wind) nextGust(gust, noise float64) float64 {
+	return GustCorrelation*gust + math.Sqrt(1-GustCorrelation*GustCorrelation)*w.Turbulence*noise
+}
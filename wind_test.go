@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindGusts(t *testing.T) {
+	// Test case 1: A steady wind never changes
+	steady := Wind{Bias: 0.01}
+	for i := 0; i < 10; i++ {
+		steady.update()
+	}
+	if steady.Force() != 0.01 {
+		t.Errorf("Expected a steady force of 0.01, but got %v", steady.Force())
+	}
+
+	// Test case 2: Gusts follow the seed
+	a, b, other := Wind{Turbulence: 0.01, Seed: 3}, Wind{Turbulence: 0.01, Seed: 3}, Wind{Turbulence: 0.01, Seed: 4}
+	differs := false
+	for i := 0; i < 100; i++ {
+		a.update()
+		b.update()
+		other.update()
+		if a.Force() != b.Force() {
+			t.Fatalf("Tick %d: expected the same gusts for the same seed, but got %v and %v", i, a.Force(), b.Force())
+		}
+		differs = differs || a.Force() != other.Force()
+	}
+	if !differs {
+		t.Errorf("Expected other gusts for another seed")
+	}
+
+	// Test case 3: Gusts stay around the turbulence strength
+	sum := 0.0
+	for i := 0; i < 10000; i++ {
+		a.update()
+		sum += a.Force() * a.Force()
+	}
+	if deviation := math.Sqrt(sum / 10000); deviation < 0.005 || deviation > 0.02 {
+		t.Errorf("Expected gusts of about 0.01, but got a deviation of %v", deviation)
+	}
+}
+
+func TestStepPhysicsWind(t *testing.T) {
+	env := NewEnvironment()
+	env.Wind = Wind{Bias: 0.01}
+
+	// Test case 1: Blows the flying lander to the right
+	gs := &GameState{LanderX: 400, LanderY: 200, Terrain: env}
+	if next, _ := StepPhysics(gs, Controls{}); next.VelocityX != 0.01 {
+		t.Errorf("Expected the wind to push the lander, but got VelocityX %v", next.VelocityX)
+	}
+
+	// Test case 2: Legs on the ground hold the lander in place
+	gs = &GameState{LanderX: 400, LanderY: 485, LeftLegOnGround: true, RightLegOnGround: true, Terrain: env}
+	if next, _ := StepPhysics(gs, Controls{}); next.VelocityX != 0 {
+		t.Errorf("Expected the legs to hold the lander, but got VelocityX %v", next.VelocityX)
+	}
+
+	// Test case 3: The heuristic still lands in a light gusty wind
+	env.Wind = Wind{Bias: 0.002, Turbulence: 0.002, Seed: 5}
+	if result := RunEpisode(PolicyPilot{Policy: HeuristicPolicy{}}, StartDistribution{}.Sample(0, env), 1000); result.Reason != Landed {
+		t.Errorf("Expected the heuristic to land in the wind, but got %+v", result)
+	}
+}